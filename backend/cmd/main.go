@@ -1,7 +1,16 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"flag"
+	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/dfanso/reddit-clone/config"
 	"github.com/dfanso/reddit-clone/internal/controllers"
@@ -9,7 +18,13 @@ import (
 	"github.com/dfanso/reddit-clone/internal/repositories"
 	"github.com/dfanso/reddit-clone/internal/routes"
 	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/auth"
 	"github.com/dfanso/reddit-clone/pkg/database"
+	"github.com/dfanso/reddit-clone/pkg/encryption"
+	"github.com/dfanso/reddit-clone/pkg/mailer"
+	"github.com/dfanso/reddit-clone/pkg/oidc"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/dfanso/reddit-clone/pkg/storage"
 
 	customMiddleware "github.com/dfanso/reddit-clone/pkg/middleware"
 	"github.com/labstack/echo/v4"
@@ -17,9 +32,98 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "keygen" {
+		runKeygen(os.Args[2:])
+		return
+	}
+
 	// Load configuration
 	cfg := config.Load()
 
+	// Load JWT signing keys before touching the database so a missing key
+	// fails fast
+	keyring, err := auth.LoadKeyring(cfg.JWT.KeysDir, cfg.JWT.ActiveKeyID)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+	jwtManager := auth.NewJWTManager(keyring, cfg.JWT.AccessTokenTTL)
+	log.Printf("Signing access tokens with key %q", keyring.ActiveKeyID())
+
+	// Load the 2FA secret encryption key. Without one, 2FA stays disabled.
+	var mfaCipher *encryption.Cipher
+	if cfg.MFA.EncryptionKey != "" {
+		mfaCipher, err = encryption.NewCipher(cfg.MFA.EncryptionKey)
+		if err != nil {
+			log.Fatalf("Failed to initialize 2FA encryption: %v", err)
+		}
+	} else {
+		log.Println("MFA_ENCRYPTION_KEY is not set, two-factor authentication is disabled")
+	}
+
+	// Key for signing pagination cursors. A random key works for a single
+	// instance but invalidates cursors on restart.
+	cursorKey := []byte(cfg.Security.CursorSecret)
+	if len(cursorKey) == 0 {
+		cursorKey = make([]byte, 32)
+		if _, err := rand.Read(cursorKey); err != nil {
+			log.Fatalf("Failed to generate cursor key: %v", err)
+		}
+		log.Println("PAGINATION_CURSOR_SECRET is not set, using a random key for this process")
+	}
+	cursorSigner := pagination.NewSigner(cursorKey)
+
+	// Key for signing data export download links. With a random key, links
+	// handed out before a restart stop working.
+	exportLinkKey := []byte(cfg.Exports.LinkSecret)
+	if len(exportLinkKey) == 0 {
+		exportLinkKey = make([]byte, 32)
+		if _, err := rand.Read(exportLinkKey); err != nil {
+			log.Fatalf("Failed to generate export link key: %v", err)
+		}
+		log.Println("DATA_EXPORT_LINK_SECRET is not set, using a random key for this process")
+	}
+
+	// Initialize blob storage for uploads. Local files are served by this
+	// server under /uploads.
+	var blobStore storage.BlobStore
+	switch cfg.Storage.Driver {
+	case "local":
+		publicURL := cfg.Storage.PublicURL
+		if publicURL == "" {
+			publicURL = "http://localhost:" + cfg.Server.Port + "/uploads"
+		}
+		blobStore, err = storage.NewLocalStore(cfg.Storage.LocalDir, publicURL)
+	case "s3":
+		blobStore, err = storage.NewS3Store(storage.S3Config{
+			Endpoint:  cfg.Storage.S3Endpoint,
+			Region:    cfg.Storage.S3Region,
+			Bucket:    cfg.Storage.S3Bucket,
+			AccessKey: cfg.Storage.S3AccessKey,
+			SecretKey: cfg.Storage.S3SecretKey,
+			PathStyle: cfg.Storage.S3PathStyle,
+			PublicURL: cfg.Storage.PublicURL,
+		})
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER %q", cfg.Storage.Driver)
+	}
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	// Initialize the mailer
+	var mail mailer.Mailer
+	switch cfg.Mail.Driver {
+	case "smtp":
+		mail = mailer.NewSMTPMailer(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From)
+	case "file":
+		mail, err = mailer.NewFileMailer(cfg.Mail.FileDir, cfg.Mail.From)
+		if err != nil {
+			log.Fatalf("Failed to initialize mailer: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown MAIL_DRIVER %q", cfg.Mail.Driver)
+	}
+
 	// Initialize PostgreSQL
 	db, err := database.NewPostgresClient(
 		cfg.Postgres.Host,
@@ -38,11 +142,37 @@ func main() {
 	// Auto Migrate the schema with GORM
 	err = db.AutoMigrate(
 		&models.User{}, // Add other models here as needed
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.UserTokenRevocation{},
+		&models.VerificationCode{},
+		&models.PasswordResetToken{},
+		&models.OAuthState{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.APIToken{},
+		&models.UserSettings{},
+		&models.Follow{},
+		&models.UserBlock{},
+		&models.UserMute{},
+		&models.ModerationAction{},
+		&models.DataExport{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	// Handler, email and Google ID uniqueness now ignores deactivated users,
+	// so drop the indexes that covered every row
+	for _, index := range []string{"idx_users_handler", "idx_users_email", "idx_users_google_id"} {
+		if db.Migrator().HasIndex(&models.User{}, index) {
+			if err := db.Migrator().DropIndex(&models.User{}, index); err != nil {
+				log.Fatalf("Failed to drop index %s: %v", index, err)
+			}
+		}
+	}
+
 	// Initialize Echo
 	e := echo.New()
 	e.HideBanner = false // Show the Echo banner
@@ -54,14 +184,124 @@ func main() {
 	e.Use(middleware.CORS())
 
 	// Initialize dependencies
-	userRepo := repositories.NewUserRepository(db)
+	var loginAttemptStore services.LoginAttemptStore
+	switch cfg.Security.LoginAttemptStore {
+	case "postgres":
+		loginAttemptStore = repositories.NewLoginAttemptRepository(db)
+	case "memory":
+		loginAttemptStore = repositories.NewMemoryLoginAttemptStore()
+	default:
+		log.Fatalf("Unknown LOGIN_ATTEMPT_STORE %q", cfg.Security.LoginAttemptStore)
+	}
+
+	userRepo := repositories.NewUserRepository(db, cursorSigner)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	revocationRepo := repositories.NewRevocationRepository(db)
+	verificationCodeRepo := repositories.NewVerificationCodeRepository(db)
+	passwordResetRepo := repositories.NewPasswordResetRepository(db)
+	oauthStateRepo := repositories.NewOAuthStateRepository(db)
+	recoveryCodeRepo := repositories.NewRecoveryCodeRepository(db)
+	apiTokenRepo := repositories.NewAPITokenRepository(db)
+	userSettingsRepo := repositories.NewUserSettingsRepository(db)
+	followRepo := repositories.NewFollowRepository(db, cursorSigner)
+	blockRepo := repositories.NewBlockRepository(db, cursorSigner)
+	muteRepo := repositories.NewMuteRepository(db, cursorSigner)
+	moderationRepo := repositories.NewModerationRepository(db)
+	dataExportRepo := repositories.NewDataExportRepository(db)
 	userService := services.NewUserService(userRepo)
-	authService := services.NewAuthService(userService)
-	userController := controllers.NewUserController(userService)
-	authController := controllers.NewAuthController(userService, authService)
+	tokenService := services.NewTokenService(refreshTokenRepo, sessionRepo, userService, jwtManager, cfg.JWT.RefreshTokenTTL)
+	revocationService := services.NewRevocationService(revocationRepo, cfg.JWT.AccessTokenTTL)
+	sessionService := services.NewSessionService(sessionRepo, tokenService)
+	loginGuard := services.NewLoginGuard(loginAttemptStore)
+	mfaService := services.NewMFAService(recoveryCodeRepo, userService, tokenService, revocationService, sessionService, jwtManager, mfaCipher, cfg.MFA.Issuer)
+	authService := services.NewAuthService(userService, tokenService, revocationService, mfaService, sessionService, loginGuard)
+	verificationService := services.NewVerificationService(verificationCodeRepo, userService, mail)
+	passwordResetService := services.NewPasswordResetService(passwordResetRepo, userService, authService, loginAttemptStore, mail, cfg.App.FrontendURL)
+	apiTokenService := services.NewAPITokenService(apiTokenRepo)
+	profileImageService := services.NewProfileImageService(userService, blobStore)
+	settingsService := services.NewSettingsService(userSettingsRepo)
+	blockService := services.NewBlockService(blockRepo, userService)
+	muteService := services.NewMuteService(muteRepo, userService)
+	moderationService := services.NewModerationService(moderationRepo, userService, authService)
+	accountService := services.NewAccountService(userRepo, authService, sessionService, blobStore, cfg.Accounts.DeletionGracePeriod)
+	followService := services.NewFollowService(followRepo, userService)
+	exportService := services.NewExportService(dataExportRepo, userService, settingsService, sessionService, apiTokenService, moderationService, followRepo, blockRepo, muteRepo, blobStore, exportLinkKey, cfg.Exports.TTL)
+	googleProvider := oidc.NewProvider(oidc.Config{
+		ClientID:     cfg.Google.ClientID,
+		ClientSecret: cfg.Google.ClientSecret,
+		RedirectURL:  cfg.Google.RedirectURL,
+		AuthURL:      cfg.Google.AuthURL,
+		TokenURL:     cfg.Google.TokenURL,
+		JWKSURL:      cfg.Google.JWKSURL,
+		Issuer:       cfg.Google.Issuer,
+		Scopes:       []string{"openid", "email", "profile"},
+	})
+	googleAuthService := services.NewGoogleAuthService(oauthStateRepo, userService, tokenService, authService, mfaService, apiTokenService, googleProvider)
+	userController := controllers.NewUserController(userService, accountService)
+	authController := controllers.NewAuthController(userService, authService, verificationService, passwordResetService)
+	oauthController := controllers.NewOAuthController(googleAuthService, cfg.App.FrontendURL)
+	mfaController := controllers.NewMFAController(mfaService)
+	sessionController := controllers.NewSessionController(sessionService)
+	adminController := controllers.NewAdminController(userService, authService, moderationService, accountService)
+	keysController := controllers.NewKeysController(jwtManager)
+	apiTokenController := controllers.NewAPITokenController(apiTokenService)
+	profileImageController := controllers.NewProfileImageController(profileImageService)
+	settingsController := controllers.NewSettingsController(settingsService)
+	followController := controllers.NewFollowController(followService)
+	blockController := controllers.NewBlockController(blockService)
+	muteController := controllers.NewMuteController(muteService)
+	accountController := controllers.NewAccountController(accountService)
+	exportController := controllers.NewExportController(exportService)
+	authenticator := customMiddleware.NewAuthenticator(jwtManager, userService, revocationService, sessionService, apiTokenService)
+
+	// Prune expired token revocations in the background
+	revocationService.StartPruner(context.Background(), time.Hour)
+
+	// Drop stale failed login counters in the background
+	loginGuard.StartPruner(context.Background(), time.Hour)
+
+	// Write session last-seen times in batches
+	sessionService.StartFlusher(context.Background(), time.Minute)
+
+	// Anonymize accounts whose deactivation grace period has passed
+	accountService.StartPurger(context.Background(), cfg.Accounts.PurgeInterval)
+
+	// Build requested data exports and delete the ones nobody downloaded
+	exportService.StartWorker(context.Background(), time.Minute)
 
 	// Register routes
-	routes.RegisterRoutes(e, userController, authController)
+	routes.RegisterRoutes(e, routes.Controllers{
+		User:     userController,
+		Auth:     authController,
+		OAuth:    oauthController,
+		MFA:      mfaController,
+		Session:  sessionController,
+		Admin:    adminController,
+		Keys:     keysController,
+		Token:    apiTokenController,
+		Image:    profileImageController,
+		Settings: settingsController,
+		Follow:   followController,
+		Block:    blockController,
+		Mute:     muteController,
+		Account:  accountController,
+		Export:   exportController,
+	}, authenticator)
+
+	// Serve locally stored uploads. Data exports share the store but are
+	// only handed out through their signed download links.
+	if cfg.Storage.Driver == "local" {
+		uploads := echo.StaticDirectoryHandler(echo.MustSubFS(e.Filesystem, cfg.Storage.LocalDir), false)
+		e.GET("/uploads/*", func(c echo.Context) error {
+			// Resolve the name the way the static handler will
+			name, err := url.PathUnescape(c.Param("*"))
+			if err != nil || strings.HasPrefix(path.Clean("/"+name)+"/", "/"+services.ExportKeyPrefix) {
+				return echo.ErrNotFound
+			}
+			return uploads(c)
+		})
+	}
 
 	// health check route
 	e.GET("/health", func(c echo.Context) error {
@@ -74,3 +314,21 @@ func main() {
 	log.Printf("Server starting on port %s", cfg.Server.Port)
 	e.Logger.Fatal(e.Start(":" + cfg.Server.Port))
 }
+
+// runKeygen writes a new P-256 signing key into the keys directory. The new
+// kid sorts after older generated ones, so it becomes the active key on the
+// next start unless JWT_ACTIVE_KID pins another.
+func runKeygen(args []string) {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	dir := fs.String("dir", "", "directory to write the key to (defaults to JWT_KEYS_DIR)")
+	fs.Parse(args)
+
+	if *dir == "" {
+		*dir = config.Load().JWT.KeysDir
+	}
+	kid, err := auth.GenerateKeyPair(*dir)
+	if err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+	fmt.Println(kid)
+}
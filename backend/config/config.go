@@ -1,7 +1,11 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,6 +14,9 @@ type Config struct {
 	Server struct {
 		Port string
 	}
+	App struct {
+		FrontendURL string
+	}
 	Postgres struct {
 		Host     string
 		Port     string
@@ -17,6 +24,57 @@ type Config struct {
 		Password string
 		DBName   string
 	}
+	JWT struct {
+		KeysDir         string
+		ActiveKeyID     string
+		AccessTokenTTL  time.Duration
+		RefreshTokenTTL time.Duration
+	}
+	Google struct {
+		ClientID     string
+		ClientSecret string
+		RedirectURL  string
+		AuthURL      string
+		TokenURL     string
+		JWKSURL      string
+		Issuer       string
+	}
+	MFA struct {
+		EncryptionKey string // base64 encoded 32-byte AES key, 2FA is off without it
+		Issuer        string
+	}
+	Security struct {
+		LoginAttemptStore string // "postgres" or "memory"
+		CursorSecret      string // HMAC key for pagination cursors, random per process when empty
+	}
+	Storage struct {
+		Driver      string // "local" or "s3"
+		LocalDir    string
+		PublicURL   string // Base URL stored files are served from
+		S3Endpoint  string
+		S3Region    string
+		S3Bucket    string
+		S3AccessKey string
+		S3SecretKey string
+		S3PathStyle bool // Needed by MinIO and most other S3-compatible servers
+	}
+	Accounts struct {
+		DeletionGracePeriod time.Duration // Time a deactivated account can be restored before it is purged
+		PurgeInterval       time.Duration
+	}
+	Exports struct {
+		LinkSecret string        // HMAC key for download links, random per process when empty
+		TTL        time.Duration // Time a finished export stays downloadable
+	}
+	Mail struct {
+		Driver       string // "smtp" or "file"
+		From         string
+		SMTPHost     string
+		SMTPPort     string
+		SMTPUsername string
+		SMTPPassword string
+		FileDir      string
+	}
 }
 
 func Load() *Config {
@@ -28,6 +86,9 @@ func Load() *Config {
 	// Server configuration
 	cfg.Server.Port = getEnv("SERVER_PORT", "8080")
 
+	// Application configuration
+	cfg.App.FrontendURL = strings.TrimRight(getEnv("FRONTEND_URL", "http://localhost:3000"), "/")
+
 	// PostgreSQL configuration
 	cfg.Postgres.Host = getEnv("POSTGRES_HOST", "localhost")
 	cfg.Postgres.Port = getEnv("POSTGRES_PORT", "5432")
@@ -35,6 +96,57 @@ func Load() *Config {
 	cfg.Postgres.Password = getEnv("POSTGRES_PASSWORD", "postgres")
 	cfg.Postgres.DBName = getEnv("POSTGRES_DB", "myapp")
 
+	// JWT configuration
+	cfg.JWT.KeysDir = getEnv("JWT_KEYS_DIR", "keys")
+	cfg.JWT.ActiveKeyID = getEnv("JWT_ACTIVE_KID", "")
+	cfg.JWT.AccessTokenTTL = getDurationEnv("JWT_ACCESS_TOKEN_TTL", 15*time.Minute)
+	cfg.JWT.RefreshTokenTTL = getDurationEnv("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour)
+
+	// Google OAuth2 / OIDC configuration. Sign-in is disabled without a client ID.
+	cfg.Google.ClientID = getEnv("GOOGLE_CLIENT_ID", "")
+	cfg.Google.ClientSecret = getEnv("GOOGLE_CLIENT_SECRET", "")
+	cfg.Google.RedirectURL = getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/api/v1/auth/google/callback")
+	cfg.Google.AuthURL = getEnv("GOOGLE_AUTH_URL", "https://accounts.google.com/o/oauth2/v2/auth")
+	cfg.Google.TokenURL = getEnv("GOOGLE_TOKEN_URL", "https://oauth2.googleapis.com/token")
+	cfg.Google.JWKSURL = getEnv("GOOGLE_JWKS_URL", "https://www.googleapis.com/oauth2/v3/certs")
+	cfg.Google.Issuer = getEnv("GOOGLE_ISSUER", "https://accounts.google.com")
+
+	// Two-factor authentication configuration
+	cfg.MFA.EncryptionKey = getEnv("MFA_ENCRYPTION_KEY", "")
+	cfg.MFA.Issuer = getEnv("MFA_ISSUER", "Reddit Clone")
+
+	// Security configuration
+	cfg.Security.LoginAttemptStore = getEnv("LOGIN_ATTEMPT_STORE", "postgres")
+	cfg.Security.CursorSecret = getEnv("PAGINATION_CURSOR_SECRET", "")
+
+	// Blob storage configuration
+	cfg.Storage.Driver = getEnv("STORAGE_DRIVER", "local")
+	cfg.Storage.LocalDir = getEnv("STORAGE_LOCAL_DIR", "uploads")
+	cfg.Storage.PublicURL = strings.TrimRight(getEnv("STORAGE_PUBLIC_URL", ""), "/")
+	cfg.Storage.S3Endpoint = getEnv("S3_ENDPOINT", "https://s3.amazonaws.com")
+	cfg.Storage.S3Region = getEnv("S3_REGION", "us-east-1")
+	cfg.Storage.S3Bucket = getEnv("S3_BUCKET", "")
+	cfg.Storage.S3AccessKey = getEnv("S3_ACCESS_KEY", "")
+	cfg.Storage.S3SecretKey = getEnv("S3_SECRET_KEY", "")
+	cfg.Storage.S3PathStyle = getBoolEnv("S3_PATH_STYLE", false)
+
+	// Account lifecycle configuration
+	cfg.Accounts.DeletionGracePeriod = getDurationEnv("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	cfg.Accounts.PurgeInterval = getDurationEnv("ACCOUNT_PURGE_INTERVAL", time.Hour)
+
+	// Personal data export configuration
+	cfg.Exports.LinkSecret = getEnv("DATA_EXPORT_LINK_SECRET", "")
+	cfg.Exports.TTL = getDurationEnv("DATA_EXPORT_TTL", 48*time.Hour)
+
+	// Mail configuration
+	cfg.Mail.Driver = getEnv("MAIL_DRIVER", "file")
+	cfg.Mail.From = getEnv("MAIL_FROM", "no-reply@localhost")
+	cfg.Mail.SMTPHost = getEnv("SMTP_HOST", "localhost")
+	cfg.Mail.SMTPPort = getEnv("SMTP_PORT", "1025")
+	cfg.Mail.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	cfg.Mail.SMTPPassword = getEnv("SMTP_PASSWORD", "")
+	cfg.Mail.FileDir = getEnv("MAIL_FILE_DIR", "tmp/mail")
+
 	return cfg
 }
 
@@ -45,3 +157,33 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getDurationEnv parses values such as "15m" or "720h", falling back to the
+// default when the variable is unset or invalid
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid duration for %s: %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// getBoolEnv parses values such as "true" or "0", falling back to the
+// default when the variable is unset or invalid
+func getBoolEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s: %q, using %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type AccountController struct {
+	accountService *services.AccountService
+}
+
+func NewAccountController(accountService *services.AccountService) *AccountController {
+	return &AccountController{
+		accountService: accountService,
+	}
+}
+
+// Deactivate deactivates the caller's account once they confirm their
+// password, or for Google users a recent sign-in. The account is purged
+// after the grace period unless an admin restores it first.
+func (c *AccountController) Deactivate(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind request body to DeactivateAccountRequest DTO
+	var req dto.DeactivateAccountRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid deactivation data", err)
+	}
+
+	purgeAfter, err := c.accountService.DeactivateSelf(ctx.Request().Context(), user, claims.SessionID, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPassword):
+			return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Password is incorrect", nil)
+		case errors.Is(err, services.ErrReauthRequired):
+			return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Sign in again to deactivate your account", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to deactivate account", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Account deactivated successfully", &dto.DeactivationResponse{PurgeAfter: purgeAfter})
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type AdminController struct {
+	userService       *services.UserService
+	authService       *services.AuthService
+	moderationService *services.ModerationService
+	accountService    *services.AccountService
+}
+
+func NewAdminController(userService *services.UserService, authService *services.AuthService, moderationService *services.ModerationService, accountService *services.AccountService) *AdminController {
+	return &AdminController{
+		userService:       userService,
+		authService:       authService,
+		moderationService: moderationService,
+		accountService:    accountService,
+	}
+}
+
+// Unlock clears a user's failed login lockout
+func (c *AdminController) Unlock(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID format", err)
+	}
+
+	user, err := c.userService.GetByID(ctx.Request().Context(), id)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
+	}
+
+	if err := c.authService.UnlockAccount(ctx.Request().Context(), user); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to unlock user", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "User unlocked successfully", nil)
+}
+
+// Restore reactivates a deactivated account during its grace period
+func (c *AdminController) Restore(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID format", err)
+	}
+
+	user, err := c.accountService.Restore(ctx.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "No deactivated user with this ID", err)
+		case errors.Is(err, services.ErrRestoreConflict):
+			return utils.ErrorResponse(ctx, http.StatusConflict, "Failed to restore user", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to restore user", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "User restored successfully", dto.NewPrivateUserResponse(user))
+}
+
+// Suspend locks a user out for the requested number of hours
+func (c *AdminController) Suspend(ctx echo.Context) error {
+	actor, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID format", err)
+	}
+
+	// Bind request body to SuspendUserRequest DTO
+	var req dto.SuspendUserRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid suspension data", err)
+	}
+
+	action, err := c.moderationService.Suspend(ctx.Request().Context(), actor, id, req.Duration(), req.Reason)
+	if err != nil {
+		return moderationErrorResponse(ctx, "Failed to suspend user", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "User suspended successfully", dto.NewModerationActionResponse(action))
+}
+
+// Ban bans a user until an admin lifts it
+func (c *AdminController) Ban(ctx echo.Context) error {
+	return c.moderate(ctx, c.moderationService.Ban, "Failed to ban user", "User banned successfully")
+}
+
+// Unban lifts a user's ban or suspension
+func (c *AdminController) Unban(ctx echo.Context) error {
+	return c.moderate(ctx, c.moderationService.Unban, "Failed to unban user", "User unbanned successfully")
+}
+
+// ModerationHistory returns the suspensions, bans and unbans of a user
+func (c *AdminController) ModerationHistory(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID format", err)
+	}
+
+	actions, err := c.moderationService.History(ctx.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get moderation history", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Moderation history retrieved successfully", dto.NewModerationActionResponses(actions))
+}
+
+type moderationFunc func(ctx context.Context, actor *models.User, userID uuid.UUID, reason string) (*models.ModerationAction, error)
+
+// moderate runs a ban or unban with the reason from the request body
+func (c *AdminController) moderate(ctx echo.Context, apply moderationFunc, failure, success string) error {
+	actor, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID format", err)
+	}
+
+	// Bind request body to ModerationReasonRequest DTO
+	var req dto.ModerationReasonRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid moderation data", err)
+	}
+
+	action, err := apply(ctx.Request().Context(), actor, id, req.Reason)
+	if err != nil {
+		return moderationErrorResponse(ctx, failure, err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, success, dto.NewModerationActionResponse(action))
+}
+
+// moderationErrorResponse maps moderation service errors to responses
+func moderationErrorResponse(ctx echo.Context, message string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
+	case errors.Is(err, services.ErrCannotModerateSelf):
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, message, err)
+	case errors.Is(err, services.ErrUserAlreadyBanned), errors.Is(err, services.ErrUserNotModerated):
+		return utils.ErrorResponse(ctx, http.StatusConflict, message, err)
+	}
+	return utils.ErrorResponse(ctx, http.StatusInternalServerError, message, err)
+}
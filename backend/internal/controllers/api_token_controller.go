@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type APITokenController struct {
+	apiTokenService *services.APITokenService
+}
+
+func NewAPITokenController(apiTokenService *services.APITokenService) *APITokenController {
+	return &APITokenController{
+		apiTokenService: apiTokenService,
+	}
+}
+
+// Create issues a personal API token for the caller
+func (c *APITokenController) Create(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind request body to CreateAPITokenRequest DTO
+	var req dto.CreateAPITokenRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid token data", err)
+	}
+
+	plaintext, token, err := c.apiTokenService.Create(ctx.Request().Context(), user, req)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyAPITokens) {
+			return utils.ErrorResponse(ctx, http.StatusConflict, "Too many active API tokens", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to create API token", err)
+	}
+
+	response := &dto.CreateAPITokenResponse{
+		APITokenResponse: dto.NewAPITokenResponse(token),
+		Token:            plaintext,
+	}
+	return utils.SuccessResponse(ctx, http.StatusCreated, "API token created, copy it now as it will not be shown again", response)
+}
+
+// List returns the caller's unrevoked API tokens
+func (c *APITokenController) List(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	tokens, err := c.apiTokenService.List(ctx.Request().Context(), user.ID)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get API tokens", err)
+	}
+
+	response := make([]*dto.APITokenResponse, len(tokens))
+	for i := range tokens {
+		response[i] = dto.NewAPITokenResponse(&tokens[i])
+	}
+	return utils.SuccessResponse(ctx, http.StatusOK, "API tokens retrieved successfully", response)
+}
+
+// Delete revokes one of the caller's API tokens
+func (c *APITokenController) Delete(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID format", err)
+	}
+
+	if err := c.apiTokenService.Revoke(ctx.Request().Context(), user.ID, id); err != nil {
+		if errors.Is(err, services.ErrAPITokenNotFound) {
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "API token not found", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to revoke API token", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "API token revoked successfully", nil)
+}
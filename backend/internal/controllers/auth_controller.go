@@ -1,24 +1,34 @@
 package controllers
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 
 	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/models"
 	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
 	"github.com/dfanso/reddit-clone/pkg/utils"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/labstack/echo/v4"
 )
 
 type AuthController struct {
-	userService *services.UserService
-	authService *services.AuthService
+	userService          *services.UserService
+	authService          *services.AuthService
+	verificationService  *services.VerificationService
+	passwordResetService *services.PasswordResetService
 }
 
-func NewAuthController(userService *services.UserService, authService *services.AuthService) *AuthController {
+func NewAuthController(userService *services.UserService, authService *services.AuthService, verificationService *services.VerificationService, passwordResetService *services.PasswordResetService) *AuthController {
 	return &AuthController{
-		userService: userService,
-		authService: authService,
+		userService:          userService,
+		authService:          authService,
+		verificationService:  verificationService,
+		passwordResetService: passwordResetService,
 	}
 }
 
@@ -44,8 +54,14 @@ func (c *AuthController) Register(ctx echo.Context) error {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to register user", err)
 	}
 
+	// Email the verification code. A failed send is not fatal since the
+	// user can ask for another code.
+	if err := c.verificationService.SendCode(ctx.Request().Context(), user); err != nil {
+		log.Printf("Failed to send verification code to user %s: %v", user.ID, err)
+	}
+
 	// Return success response
-	return utils.SuccessResponse(ctx, http.StatusCreated, "User registered successfully", user)
+	return utils.SuccessResponse(ctx, http.StatusCreated, "User registered successfully", dto.NewPrivateUserResponse(user))
 }
 
 func (c *AuthController) Login(ctx echo.Context) error {
@@ -64,23 +80,332 @@ func (c *AuthController) Login(ctx echo.Context) error {
 	}
 
 	// Authenticate the user via the auth service
-	user, err := c.authService.Login(ctx.Request().Context(), req)
+	// and issue an access token
+	result, err := c.authService.Login(ctx.Request().Context(), req, clientInfo(ctx))
 	if err != nil {
-		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Login failed", err)
+		return loginErrorResponse(ctx, err)
 	}
 
-	// TODO: Generate JWT token
+	if result.MFARequired {
+		return utils.SuccessResponse(ctx, http.StatusOK, "Two-factor code required", result)
+	}
 
-	//retrun  jwt token with user object
+	// Return the access token with the sanitized user
+	return utils.SuccessResponse(ctx, http.StatusOK, "Login successful", result)
+}
 
-	// Return success response
-	return utils.SuccessResponse(ctx, http.StatusOK, "Login successful", user)
+func (c *AuthController) LoginMFA(ctx echo.Context) error {
+	// Bind request body to MFALoginRequest DTO
+	var req dto.MFALoginRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid login data", err)
+	}
+
+	// Check the second factor and issue tokens
+	result, err := c.authService.LoginMFA(ctx.Request().Context(), req, clientInfo(ctx))
+	if err != nil {
+		return loginErrorResponse(ctx, err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Login successful", result)
+}
+
+func (c *AuthController) VerifyEmail(ctx echo.Context) error {
+	// Bind request body to VerifyEmailRequest DTO
+	var req dto.VerifyEmailRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid verification data", err)
+	}
+
+	user, err := c.verificationService.Verify(ctx.Request().Context(), req.Email, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidVerificationCode):
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Email verification failed", err)
+		case errors.Is(err, services.ErrTooManyAttempts):
+			return utils.ErrorResponse(ctx, http.StatusTooManyRequests, "Email verification failed", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Email verification failed", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Email verified successfully", dto.NewPrivateUserResponse(user))
+}
+
+func (c *AuthController) ResendVerification(ctx echo.Context) error {
+	// Bind request body to ResendVerificationRequest DTO
+	var req dto.ResendVerificationRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid resend data", err)
+	}
+
+	if err := c.verificationService.Resend(ctx.Request().Context(), req.Email); err != nil {
+		if errors.Is(err, services.ErrResendThrottled) {
+			return utils.ErrorResponse(ctx, http.StatusTooManyRequests, "Failed to resend verification code", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to resend verification code", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "If the account needs verification, a new code has been sent", nil)
 }
 
-//TODO: Profile
+func (c *AuthController) Refresh(ctx echo.Context) error {
+	// Bind request body to RefreshRequest DTO
+	var req dto.RefreshRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
 
-//TODO: Logout
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid refresh data", err)
+	}
 
-//TODO: Forgot Password
+	// Rotate the refresh token via the auth service
+	tokens, err := c.authService.Refresh(ctx.Request().Context(), req)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Token refresh failed", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Token refreshed successfully", tokens)
+}
+
+func (c *AuthController) CompleteSignup(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
 
-//TODO: Reset Password
+	// Bind request body to CompleteSignupRequest DTO
+	var req dto.CompleteSignupRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid signup data", err)
+	}
+
+	if user.Stage != models.StageEmailVerified && user.Stage != models.StageGoogleSSO {
+		return utils.ErrorResponse(ctx, http.StatusConflict, "Signup cannot be completed at this stage", nil)
+	}
+
+	updated, err := c.userService.CompleteSignup(ctx.Request().Context(), user, req)
+	if err != nil {
+		if errors.Is(err, services.ErrHandlerTaken) {
+			return utils.ErrorResponse(ctx, http.StatusConflict, "Failed to complete signup", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to complete signup", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Signup completed successfully", dto.NewPrivateUserResponse(updated))
+}
+
+// Profile returns the authenticated user's own profile
+func (c *AuthController) Profile(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Profile retrieved successfully", dto.NewPrivateUserResponse(user))
+}
+
+// UpdateProfile edits the authenticated user's name, description, avatar
+// and banner
+func (c *AuthController) UpdateProfile(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind request body to UpdateProfileRequest DTO
+	var req dto.UpdateProfileRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid profile data", err)
+	}
+
+	updated, err := c.userService.UpdateProfile(ctx.Request().Context(), user, req)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to update profile", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Profile updated successfully", dto.NewPrivateUserResponse(updated))
+}
+
+// ChangePassword sets a new password for the authenticated user, who must
+// confirm the current one. Every other session is signed out.
+func (c *AuthController) ChangePassword(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind request body to ChangePasswordRequest DTO
+	var req dto.ChangePasswordRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid password data", err)
+	}
+
+	if err := c.authService.ChangePassword(ctx.Request().Context(), user, claims.SessionID, req); err != nil {
+		if errors.Is(err, services.ErrInvalidPassword) {
+			return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Current password is incorrect", nil)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to change password", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Password changed successfully", nil)
+}
+
+func (c *AuthController) Logout(ctx echo.Context) error {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	if err := c.authService.Logout(ctx.Request().Context(), claims); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Logout failed", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Logged out successfully", nil)
+}
+
+func (c *AuthController) LogoutAll(ctx echo.Context) error {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	if err := c.authService.LogoutAll(ctx.Request().Context(), claims.UserID); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Logout failed", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Logged out of all sessions successfully", nil)
+}
+
+func (c *AuthController) ForgotPassword(ctx echo.Context) error {
+	// Bind request body to ForgotPasswordRequest DTO
+	var req dto.ForgotPasswordRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid forgot password data", err)
+	}
+
+	if err := c.passwordResetService.ForgotPassword(ctx.Request().Context(), req.Email, ctx.RealIP()); err != nil {
+		if errors.Is(err, services.ErrResetThrottled) {
+			return utils.ErrorResponse(ctx, http.StatusTooManyRequests, "Too many password reset requests", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to request password reset", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "If an account exists for this email, a reset link has been sent", nil)
+}
+
+func (c *AuthController) ResetPassword(ctx echo.Context) error {
+	// Bind request body to ResetPasswordRequest DTO
+	var req dto.ResetPasswordRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid reset password data", err)
+	}
+
+	if err := c.passwordResetService.ResetPassword(ctx.Request().Context(), req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Password reset failed", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Password reset failed", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Password reset successfully", nil)
+}
+
+// clientInfo describes the device making the request for session tracking
+func clientInfo(ctx echo.Context) dto.ClientInfo {
+	return dto.ClientInfo{
+		UserAgent: ctx.Request().UserAgent(),
+		IP:        ctx.RealIP(),
+	}
+}
+
+// loginErrorResponse answers lockouts with 429 and a Retry-After header,
+// banned and suspended accounts with 403 and every other login failure
+// with 401
+func loginErrorResponse(ctx echo.Context, err error) error {
+	var locked *services.LoginLockedError
+	if errors.As(err, &locked) {
+		ctx.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		return utils.ErrorResponse(ctx, http.StatusTooManyRequests, "Login failed", err)
+	}
+	var suspended *services.AccountSuspendedError
+	if errors.As(err, &suspended) {
+		return utils.ErrorResponse(ctx, http.StatusForbidden, "Account is suspended", err)
+	}
+	if errors.Is(err, services.ErrAccountBanned) {
+		return utils.ErrorResponse(ctx, http.StatusForbidden, "Account is banned", err)
+	}
+	return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Login failed", err)
+}
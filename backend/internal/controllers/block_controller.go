@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type BlockController struct {
+	blockService *services.BlockService
+}
+
+func NewBlockController(blockService *services.BlockService) *BlockController {
+	return &BlockController{
+		blockService: blockService,
+	}
+}
+
+// List returns the users the caller blocked, one cursor page at a time
+func (c *BlockController) List(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind query parameters to PageQuery DTO
+	var query dto.PageQuery
+	if err := ctx.Bind(&query); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	// Validate the DTO
+	if err := query.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	page, err := c.blockService.List(ctx.Request().Context(), user.ID, query.Params())
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid cursor", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get blocked users", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Blocked users retrieved successfully", dto.NewBlockPageResponse(page))
+}
+
+// Create blocks the user named in the request body
+func (c *BlockController) Create(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind request body to CreateBlockRequest DTO
+	var req dto.CreateBlockRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid block data", err)
+	}
+
+	if err := c.blockService.Block(ctx.Request().Context(), user, req.Handler()); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
+		case errors.Is(err, services.ErrCannotBlockSelf):
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Cannot block yourself", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to block user", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "User blocked successfully", nil)
+}
+
+// Delete unblocks the user in the :handle path parameter
+func (c *BlockController) Delete(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	if err := c.blockService.Unblock(ctx.Request().Context(), user, handleParam(ctx)); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to unblock user", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "User unblocked successfully", nil)
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ExportController struct {
+	exportService *services.ExportService
+}
+
+func NewExportController(exportService *services.ExportService) *ExportController {
+	return &ExportController{
+		exportService: exportService,
+	}
+}
+
+// Create queues an export of the caller's personal data. It is built in
+// the background; its download link shows up in List once ready.
+func (c *ExportController) Create(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	export, err := c.exportService.Request(ctx.Request().Context(), user.ID)
+	if err != nil {
+		var limited *services.ExportRateLimitedError
+		if errors.As(err, &limited) {
+			ctx.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+			return utils.ErrorResponse(ctx, http.StatusTooManyRequests, "Too many data exports requested", err)
+		}
+		if errors.Is(err, services.ErrExportInProgress) {
+			return utils.ErrorResponse(ctx, http.StatusConflict, "A data export is already being prepared", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to request data export", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusAccepted, "Data export requested successfully", dto.NewDataExportResponse(export, ""))
+}
+
+// List returns the caller's recent data exports, with a download link for
+// those that are ready
+func (c *ExportController) List(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	exports, err := c.exportService.List(ctx.Request().Context(), user.ID)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get data exports", err)
+	}
+
+	now := time.Now()
+	response := make([]*dto.DataExportResponse, len(exports))
+	for i := range exports {
+		var downloadURL string
+		if exports[i].Status == models.ExportReady && exports[i].ExpiresAt.After(now) {
+			downloadURL = c.downloadURL(ctx, &exports[i])
+		}
+		response[i] = dto.NewDataExportResponse(&exports[i], downloadURL)
+	}
+	return utils.SuccessResponse(ctx, http.StatusOK, "Data exports retrieved successfully", response)
+}
+
+// Download streams an export archive. The signed link is the only
+// credential, so it works from a plain browser download, and it works once.
+func (c *ExportController) Download(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID format", err)
+	}
+	expires, err := strconv.ParseInt(ctx.QueryParam("expires"), 10, 64)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusForbidden, "Invalid or expired download link", nil)
+	}
+
+	body, export, err := c.exportService.Download(ctx.Request().Context(), id, expires, ctx.QueryParam("signature"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidExportLink) {
+			return utils.ErrorResponse(ctx, http.StatusForbidden, "Invalid or expired download link", err)
+		}
+		if errors.Is(err, services.ErrExportUnavailable) {
+			return utils.ErrorResponse(ctx, http.StatusGone, "Data export is no longer available", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to download data export", err)
+	}
+	defer body.Close()
+
+	header := ctx.Response().Header()
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"data-export-%s.zip\"", export.CreatedAt.Format("2006-01-02")))
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(export.Size, 10))
+	header.Set("Cache-Control", "no-store")
+	return ctx.Stream(http.StatusOK, "application/zip", body)
+}
+
+// downloadURL builds the signed download link of a ready export on the
+// host the request came in on
+func (c *ExportController) downloadURL(ctx echo.Context, export *models.DataExport) string {
+	expires, signature := c.exportService.DownloadSignature(export)
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signature)
+	return fmt.Sprintf("%s://%s/api/v1/me/exports/%s/download?%s", ctx.Scheme(), ctx.Request().Host, export.ID, query.Encode())
+}
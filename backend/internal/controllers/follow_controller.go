@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type FollowController struct {
+	followService *services.FollowService
+}
+
+func NewFollowController(followService *services.FollowService) *FollowController {
+	return &FollowController{
+		followService: followService,
+	}
+}
+
+// Follow makes the caller follow the user in the :handle path parameter
+func (c *FollowController) Follow(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	if err := c.followService.Follow(ctx.Request().Context(), user, handleParam(ctx)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
+		case errors.Is(err, services.ErrCannotFollowSelf):
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Cannot follow yourself", err)
+		case errors.Is(err, services.ErrFollowNotAllowed):
+			return utils.ErrorResponse(ctx, http.StatusForbidden, "Cannot follow this user", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to follow user", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "User followed successfully", nil)
+}
+
+// Unfollow stops the caller following the user in the :handle path parameter
+func (c *FollowController) Unfollow(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	if err := c.followService.Unfollow(ctx.Request().Context(), user, handleParam(ctx)); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to unfollow user", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "User unfollowed successfully", nil)
+}
+
+// Followers lists the users following the user in the :handle path
+// parameter, one cursor page at a time
+func (c *FollowController) Followers(ctx echo.Context) error {
+	return c.list(ctx, c.followService.Followers, "Followers")
+}
+
+// Following lists the users the user in the :handle path parameter
+// follows, one cursor page at a time
+func (c *FollowController) Following(ctx echo.Context) error {
+	return c.list(ctx, c.followService.Following, "Following")
+}
+
+func (c *FollowController) list(ctx echo.Context, find func(ctx context.Context, handler string, params pagination.Params) (*pagination.Page[types.FollowListEntry], error), name string) error {
+	// Bind query parameters to PageQuery DTO
+	var query dto.PageQuery
+	if err := ctx.Bind(&query); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	// Validate the DTO
+	if err := query.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	page, err := find(ctx.Request().Context(), handleParam(ctx), query.Params())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
+		case errors.Is(err, pagination.ErrInvalidCursor):
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid cursor", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get "+strings.ToLower(name), err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, name+" retrieved successfully", dto.NewFollowPageResponse(page))
+}
+
+// handleParam returns the :handle path parameter without the "u/" prefix
+func handleParam(ctx echo.Context) string {
+	return strings.TrimPrefix(ctx.Param("handle"), dto.HandlePrefix)
+}
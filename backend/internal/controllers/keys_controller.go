@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/labstack/echo/v4"
+)
+
+type KeysController struct {
+	jwtManager *auth.JWTManager
+}
+
+func NewKeysController(jwtManager *auth.JWTManager) *KeysController {
+	return &KeysController{
+		jwtManager: jwtManager,
+	}
+}
+
+// JWKS publishes the access token verification keys. The set is served
+// as-is rather than wrapped, since JWKS clients expect the RFC 7517 document.
+func (c *KeysController) JWKS(ctx echo.Context) error {
+	ctx.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return ctx.JSON(http.StatusOK, c.jwtManager.JWKS())
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type MFAController struct {
+	mfaService *services.MFAService
+}
+
+func NewMFAController(mfaService *services.MFAService) *MFAController {
+	return &MFAController{
+		mfaService: mfaService,
+	}
+}
+
+func (c *MFAController) Enroll(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	result, err := c.mfaService.Enroll(ctx.Request().Context(), user)
+	if err != nil {
+		return mfaErrorResponse(ctx, "Failed to start two-factor enrollment", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Scan the code with your authenticator app, then confirm it", result)
+}
+
+func (c *MFAController) Confirm(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind request body to MFACodeRequest DTO
+	var req dto.MFACodeRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid two-factor data", err)
+	}
+
+	codes, err := c.mfaService.Confirm(ctx.Request().Context(), user, req.Code)
+	if err != nil {
+		return mfaErrorResponse(ctx, "Failed to enable two-factor authentication", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Two-factor authentication enabled", dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (c *MFAController) Disable(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind request body to MFADisableRequest DTO
+	var req dto.MFADisableRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid two-factor data", err)
+	}
+
+	if err := c.mfaService.Disable(ctx.Request().Context(), user, claims.SessionID, req); err != nil {
+		return mfaErrorResponse(ctx, "Failed to disable two-factor authentication", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+func (c *MFAController) RegenerateRecoveryCodes(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind request body to MFACodeRequest DTO
+	var req dto.MFACodeRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid two-factor data", err)
+	}
+
+	codes, err := c.mfaService.RegenerateRecoveryCodes(ctx.Request().Context(), user, req.Code)
+	if err != nil {
+		return mfaErrorResponse(ctx, "Failed to regenerate recovery codes", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Recovery codes regenerated", dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// mfaErrorResponse maps MFA service errors to status codes
+func mfaErrorResponse(ctx echo.Context, message string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrMFADisabled):
+		return utils.ErrorResponse(ctx, http.StatusServiceUnavailable, message, err)
+	case errors.Is(err, services.ErrMFAAlreadyEnabled),
+		errors.Is(err, services.ErrMFANotEnrolled),
+		errors.Is(err, services.ErrMFANotEnabled):
+		return utils.ErrorResponse(ctx, http.StatusConflict, message, err)
+	case errors.Is(err, services.ErrInvalidMFACode),
+		errors.Is(err, services.ErrInvalidPassword):
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, message, err)
+	case errors.Is(err, services.ErrReauthRequired):
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, message, err)
+	}
+	return utils.ErrorResponse(ctx, http.StatusInternalServerError, message, err)
+}
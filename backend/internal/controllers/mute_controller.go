@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type MuteController struct {
+	muteService *services.MuteService
+}
+
+func NewMuteController(muteService *services.MuteService) *MuteController {
+	return &MuteController{
+		muteService: muteService,
+	}
+}
+
+// List returns the users the caller muted, one cursor page at a time
+func (c *MuteController) List(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind query parameters to PageQuery DTO
+	var query dto.PageQuery
+	if err := ctx.Bind(&query); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	// Validate the DTO
+	if err := query.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	page, err := c.muteService.List(ctx.Request().Context(), user.ID, query.Params())
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid cursor", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get muted users", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Muted users retrieved successfully", dto.NewMutePageResponse(page))
+}
+
+// Create mutes the user named in the request body
+func (c *MuteController) Create(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	// Bind request body to CreateMuteRequest DTO
+	var req dto.CreateMuteRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid mute data", err)
+	}
+
+	if err := c.muteService.Mute(ctx.Request().Context(), user, req.Handler()); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
+		case errors.Is(err, services.ErrCannotMuteSelf):
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Cannot mute yourself", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to mute user", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "User muted successfully", nil)
+}
+
+// Delete unmutes the user in the :handle path parameter
+func (c *MuteController) Delete(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	if err := c.muteService.Unmute(ctx.Request().Context(), user, handleParam(ctx)); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to unmute user", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "User unmuted successfully", nil)
+}
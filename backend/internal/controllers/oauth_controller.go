@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// oauthStateCookie binds a sign-in attempt to the browser that started it
+const oauthStateCookie = "oauth_state"
+
+type OAuthController struct {
+	googleAuthService *services.GoogleAuthService
+	frontendURL       string
+}
+
+func NewOAuthController(googleAuthService *services.GoogleAuthService, frontendURL string) *OAuthController {
+	return &OAuthController{
+		googleAuthService: googleAuthService,
+		frontendURL:       frontendURL,
+	}
+}
+
+// GoogleLogin redirects the browser to Google's consent screen
+func (c *OAuthController) GoogleLogin(ctx echo.Context) error {
+	authURL, state, err := c.googleAuthService.Begin(ctx.Request().Context())
+	if err != nil {
+		if errors.Is(err, services.ErrGoogleSSODisabled) {
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "Google sign-in unavailable", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to start Google sign-in", err)
+	}
+
+	ctx.SetCookie(&http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   ctx.IsTLS(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return ctx.Redirect(http.StatusFound, authURL)
+}
+
+// GoogleCallback completes the sign-in and hands the tokens to the frontend
+// in the URL fragment. Users who still need to finish signup are sent to
+// the additional details page, and users with 2FA to the second step.
+func (c *OAuthController) GoogleCallback(ctx echo.Context) error {
+	if providerErr := ctx.QueryParam("error"); providerErr != "" {
+		return c.redirectWithError(ctx, providerErr)
+	}
+
+	state := ctx.QueryParam("state")
+	code := ctx.QueryParam("code")
+	cookie, err := ctx.Cookie(oauthStateCookie)
+	if state == "" || code == "" || err != nil || cookie.Value != state {
+		return c.redirectWithError(ctx, "invalid_state")
+	}
+
+	// The state is single-use, so drop the cookie either way
+	ctx.SetCookie(&http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   ctx.IsTLS(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	result, err := c.googleAuthService.Callback(ctx.Request().Context(), state, code, clientInfo(ctx))
+	if err != nil {
+		ctx.Logger().Errorf("Google sign-in failed: %v", err)
+		var suspended *services.AccountSuspendedError
+		switch {
+		case errors.Is(err, services.ErrGoogleEmailUnverified):
+			return c.redirectWithError(ctx, "email_not_verified")
+		case errors.Is(err, services.ErrAccountBanned):
+			return c.redirectWithError(ctx, "account_banned")
+		case errors.As(err, &suspended):
+			return c.redirectWithError(ctx, "account_suspended")
+		}
+		return c.redirectWithError(ctx, "sign_in_failed")
+	}
+
+	// With 2FA on, the frontend collects the code and finishes the login
+	// through the 2FA login endpoint
+	if result.MFAToken != "" {
+		fragment := url.Values{}
+		fragment.Set("mfa_token", result.MFAToken)
+		return ctx.Redirect(http.StatusFound, fmt.Sprintf("%s/login/2fa#%s", c.frontendURL, fragment.Encode()))
+	}
+
+	fragment := url.Values{}
+	fragment.Set("access_token", result.Tokens.AccessToken)
+	fragment.Set("refresh_token", result.Tokens.RefreshToken)
+	fragment.Set("token_type", result.Tokens.TokenType)
+	fragment.Set("expires_in", strconv.Itoa(result.Tokens.ExpiresIn))
+
+	page := "/auth/callback"
+	if result.NeedsDetails {
+		page = "/signup/details"
+	}
+	return ctx.Redirect(http.StatusFound, fmt.Sprintf("%s%s#%s", c.frontendURL, page, fragment.Encode()))
+}
+
+func (c *OAuthController) redirectWithError(ctx echo.Context, reason string) error {
+	return ctx.Redirect(http.StatusFound, fmt.Sprintf("%s/login?error=%s", c.frontendURL, url.QueryEscape(reason)))
+}
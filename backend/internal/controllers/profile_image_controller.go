@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/imaging"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+type ProfileImageController struct {
+	profileImageService *services.ProfileImageService
+}
+
+func NewProfileImageController(profileImageService *services.ProfileImageService) *ProfileImageController {
+	return &ProfileImageController{
+		profileImageService: profileImageService,
+	}
+}
+
+// UploadAvatar replaces the caller's avatar with the image in the "file"
+// form field
+func (c *ProfileImageController) UploadAvatar(ctx echo.Context) error {
+	return c.upload(ctx, services.ProfileImageAvatar, "Avatar")
+}
+
+// UploadBanner replaces the caller's banner with the image in the "file"
+// form field
+func (c *ProfileImageController) UploadBanner(ctx echo.Context) error {
+	return c.upload(ctx, services.ProfileImageBanner, "Banner")
+}
+
+func (c *ProfileImageController) upload(ctx echo.Context, kind services.ProfileImageKind, name string) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "A multipart \"file\" field is required", err)
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid upload", err)
+	}
+	defer file.Close()
+
+	// Read one byte past the limit so oversized files are detected
+	data, err := io.ReadAll(io.LimitReader(file, services.MaxProfileImageBytes+1))
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid upload", err)
+	}
+
+	updated, err := c.profileImageService.Upload(ctx.Request().Context(), user, kind, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, imaging.ErrTooLarge):
+			return utils.ErrorResponse(ctx, http.StatusRequestEntityTooLarge, "Image file is too large", err)
+		case errors.Is(err, imaging.ErrUnsupportedType):
+			return utils.ErrorResponse(ctx, http.StatusUnsupportedMediaType, "Unsupported image type", err)
+		case errors.Is(err, imaging.ErrTooManyPixels), errors.Is(err, imaging.ErrInvalidImage):
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid image", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to upload "+string(kind), err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, name+" uploaded successfully", dto.NewPrivateUserResponse(updated))
+}
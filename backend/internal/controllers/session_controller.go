@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type SessionController struct {
+	sessionService *services.SessionService
+}
+
+func NewSessionController(sessionService *services.SessionService) *SessionController {
+	return &SessionController{
+		sessionService: sessionService,
+	}
+}
+
+// List returns the caller's active sessions
+func (c *SessionController) List(ctx echo.Context) error {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	sessions, err := c.sessionService.List(ctx.Request().Context(), claims.UserID)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get sessions", err)
+	}
+
+	response := make([]*dto.SessionResponse, len(sessions))
+	for i := range sessions {
+		response[i] = dto.NewSessionResponse(&sessions[i], claims.SessionID)
+	}
+	return utils.SuccessResponse(ctx, http.StatusOK, "Sessions retrieved successfully", response)
+}
+
+// Delete signs out one of the caller's sessions
+func (c *SessionController) Delete(ctx echo.Context) error {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID format", err)
+	}
+
+	if err := c.sessionService.Revoke(ctx.Request().Context(), claims.UserID, id); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return utils.ErrorResponse(ctx, http.StatusNotFound, "Session not found", err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to delete session", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Session deleted successfully", nil)
+}
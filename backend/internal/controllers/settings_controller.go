@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// maxSettingsPatchBytes bounds the size of a settings patch
+const maxSettingsPatchBytes = 16 << 10
+
+type SettingsController struct {
+	settingsService *services.SettingsService
+}
+
+func NewSettingsController(settingsService *services.SettingsService) *SettingsController {
+	return &SettingsController{
+		settingsService: settingsService,
+	}
+}
+
+// Get returns the caller's settings
+func (c *SettingsController) Get(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	settings, err := c.settingsService.Get(ctx.Request().Context(), user.ID)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get settings", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Settings retrieved successfully", settings)
+}
+
+// Patch applies a JSON merge patch (RFC 7396) to the caller's settings.
+// The body is sent as application/merge-patch+json or application/json.
+func (c *SettingsController) Patch(ctx echo.Context) error {
+	user, ok := middleware.UserFromContext(ctx)
+	if !ok {
+		return utils.ErrorResponse(ctx, http.StatusUnauthorized, "Authentication required", nil)
+	}
+
+	switch ctx.Request().Header.Get(echo.HeaderContentType) {
+	case "application/merge-patch+json", echo.MIMEApplicationJSON, echo.MIMEApplicationJSONCharsetUTF8:
+	default:
+		return utils.ErrorResponse(ctx, http.StatusUnsupportedMediaType, "Settings must be patched with application/merge-patch+json", nil)
+	}
+
+	// Read one byte past the limit so oversized patches are detected
+	patch, err := io.ReadAll(io.LimitReader(ctx.Request().Body, maxSettingsPatchBytes+1))
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
+	if len(patch) > maxSettingsPatchBytes {
+		return utils.ErrorResponse(ctx, http.StatusRequestEntityTooLarge, "Settings patch is too large", nil)
+	}
+
+	settings, err := c.settingsService.Patch(ctx.Request().Context(), user.ID, patch)
+	if err != nil {
+		var invalid *services.InvalidSettingsError
+		if errors.As(err, &invalid) {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid settings", invalid.Err)
+		}
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to update settings", err)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "Settings updated successfully", settings)
+}
@@ -1,15 +1,16 @@
 package controllers
 
 import (
-	"bytes"
-	"fmt"
-	"io"
+	"errors"
 	"net/http"
-	"strconv"
-	"time"
+	"strings"
 
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
 	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/policy"
 	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
 	"github.com/dfanso/reddit-clone/pkg/utils"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/google/uuid"
@@ -17,12 +18,14 @@ import (
 )
 
 type UserController struct {
-	service *services.UserService
+	service        *services.UserService
+	accountService *services.AccountService
 }
 
-func NewUserController(service *services.UserService) *UserController {
+func NewUserController(service *services.UserService, accountService *services.AccountService) *UserController {
 	return &UserController{
-		service: service,
+		service:        service,
+		accountService: accountService,
 	}
 }
 
@@ -31,26 +34,36 @@ func (c *UserController) GetAll(ctx echo.Context) error {
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get users", err)
 	}
-	return utils.SuccessResponse(ctx, http.StatusOK, "Users retrieved successfully", users)
+	return utils.SuccessResponse(ctx, http.StatusOK, "Users retrieved successfully", dto.NewPrivateUserResponses(users))
 }
 
+// GetPaginated lists users one cursor page at a time, with optional
+// filters, handler/email prefix search and sorting
 func (c *UserController) GetPaginated(ctx echo.Context) error {
-	fmt.Println("GetPaginated called")
-
-	// Extract page and limit from query parameters
-	pageStr := ctx.QueryParam("page")
-	limitStr := ctx.QueryParam("limit")
+	// Bind query parameters to ListUsersQuery DTO
+	var query dto.ListUsersQuery
+	if err := ctx.Bind(&query); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid query parameters", err)
+	}
 
-	page, _ := strconv.Atoi(pageStr) // this like parsInt
-	limit, _ := strconv.Atoi(limitStr)
+	// Validate the DTO
+	if err := query.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid query parameters", err)
+	}
 
 	// Call the service method
-	result, err := c.service.FindPaginated(ctx.Request().Context(), nil, page, limit)
+	result, err := c.service.FindPage(ctx.Request().Context(), query.Filter(), query.Params())
 	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid cursor", err)
+		}
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get paginated users", err)
 	}
 
-	return utils.SuccessResponse(ctx, http.StatusOK, "Paginated users retrieved successfully", result)
+	return utils.SuccessResponse(ctx, http.StatusOK, "Paginated users retrieved successfully", dto.NewUserPageResponse(result))
 }
 
 func (c *UserController) GetByID(ctx echo.Context) error {
@@ -69,32 +82,67 @@ func (c *UserController) GetByID(ctx echo.Context) error {
 		return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
 	}
 
-	return utils.SuccessResponse(ctx, http.StatusOK, "User retrieved successfully", user)
+	// Only the user themselves and admins see the private fields
+	actor, _ := middleware.UserFromContext(ctx)
+	if policy.CanManageUser(actor, user.ID.String()) {
+		return utils.SuccessResponse(ctx, http.StatusOK, "User retrieved successfully", dto.NewPrivateUserResponse(user))
+	}
+	return utils.SuccessResponse(ctx, http.StatusOK, "User retrieved successfully", dto.NewPublicUserResponse(user))
 }
 
-func (c *UserController) Create(ctx echo.Context) error {
-	var user models.User
-	body, _ := io.ReadAll(ctx.Request().Body)
-	fmt.Printf("Raw Request Body: %s\n", string(body))
-	ctx.Request().Body = io.NopCloser(bytes.NewBuffer(body))
+// GetByHandle returns the public profile of the user with the given
+// handler. Profiles of users who have not finished signup or are banned
+// are not found.
+func (c *UserController) GetByHandle(ctx echo.Context) error {
+	handler := strings.TrimPrefix(ctx.Param("handle"), dto.HandlePrefix)
+	if handler == "" {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Handle is required", nil)
+	}
 
-	if err := ctx.Bind(&user); err != nil {
+	user, err := c.service.GetPublicByHandler(ctx.Request().Context(), handler)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to get user", err)
+	}
+	if user == nil {
+		return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", nil)
+	}
+
+	return utils.SuccessResponse(ctx, http.StatusOK, "User retrieved successfully", dto.NewPublicUserResponse(user))
+}
+
+// Create adds a user on behalf of an admin
+func (c *UserController) Create(ctx echo.Context) error {
+	// Bind request body to CreateUserRequest DTO
+	var req dto.CreateUserRequest
+	if err := ctx.Bind(&req); err != nil {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
 	}
 
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
+		if e, ok := err.(validation.Errors); ok {
+			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
+		}
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid user data", err)
+	}
+
+	user := models.User{
+		Email:    req.Email,
+		Handler:  req.Handler,
+		Name:     req.Name,
+		Password: req.Password,
+		Role:     req.Role,
+		Status:   req.Status,
+		Stage:    req.Stage,
+	}
 	if user.Role == "" {
 		user.Role = models.RoleUser
 	}
 	if user.Status == "" {
 		user.Status = models.StatusUnverified
 	}
-
-	// Validate all fields
-	if err := user.Validate(); err != nil {
-		if e, ok := err.(validation.Errors); ok {
-			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
-		}
-		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid user data", err)
+	if user.Stage == "" {
+		user.Stage = models.StageEmailVerification
 	}
 
 	// Hash password
@@ -102,8 +150,7 @@ func (c *UserController) Create(ctx echo.Context) error {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to process password", err)
 	}
 
-	filter := map[string]interface{}{"email": user.Email}
-	existingUser, err := c.service.FindOne(ctx.Request().Context(), filter)
+	existingUser, err := c.service.FindByEmail(ctx.Request().Context(), user.Email)
 	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Error checking for existing user", err)
 	}
@@ -116,43 +163,63 @@ func (c *UserController) Create(ctx echo.Context) error {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to create user", err)
 	}
 
-	return utils.SuccessResponse(ctx, http.StatusCreated, "User created successfully", createdUser)
+	return utils.SuccessResponse(ctx, http.StatusCreated, "User created successfully", dto.NewPrivateUserResponse(createdUser))
 }
 
+// Update edits a user's profile. Admins may also change role, status,
+// stage and karma; passwords are changed through /me/password instead.
 func (c *UserController) Update(ctx echo.Context) error {
 	id := ctx.Param("id")
 	if id == "" {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "User ID is required", nil)
 	}
 
-	var user models.User
-	if err := ctx.Bind(&user); err != nil {
-		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID format", err)
+	}
+
+	user, err := c.service.GetByID(ctx.Request().Context(), parsedID)
+	if err != nil {
+		return utils.ErrorResponse(ctx, http.StatusNotFound, "User not found", err)
 	}
 
-	user.ID = uuid.MustParse(id)
-	user.UpdatedAt = time.Now()
+	// Bind request body to UpdateUserRequest DTO
+	var req dto.UpdateUserRequest
+	if err := ctx.Bind(&req); err != nil {
+		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid request body", err)
+	}
 
-	// Validate fields
-	if err := user.ValidateUpdate(); err != nil {
+	// Validate the DTO
+	if err := req.Validate(); err != nil {
 		if e, ok := err.(validation.Errors); ok {
 			return utils.ErrorResponse(ctx, http.StatusBadRequest, "Validation failed", e)
 		}
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid user data", err)
 	}
 
-	// Hash password if provided
-	if user.Password != "" {
-		if err := user.HashPassword(); err != nil {
-			return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to process password", err)
+	// Bans are lifted through the audited unban endpoint
+	if req.Status != nil && *req.Status != user.Status && user.Status == models.StatusBanned {
+		return utils.ErrorResponse(ctx, http.StatusConflict, "User is banned, use the unban endpoint to lift it", nil)
+	}
+
+	// Only admins may touch role, status, stage or karma
+	actor, _ := middleware.UserFromContext(ctx)
+	if req.ChangesPrivilegedFields(user) {
+		if !policy.CanChangePrivilegedFields(actor) {
+			return utils.ErrorResponse(ctx, http.StatusForbidden, "Not allowed to change role, status, stage or karma", nil)
+		}
+		if err := c.service.UpdatePrivileged(ctx.Request().Context(), user, req); err != nil {
+			return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to update user", err)
 		}
 	}
 
-	if err := c.service.Update(ctx.Request().Context(), &user); err != nil {
+	updated, err := c.service.UpdateProfile(ctx.Request().Context(), user, req.UpdateProfileRequest)
+	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to update user", err)
 	}
 
-	return utils.SuccessResponse(ctx, http.StatusOK, "User updated successfully", user)
+	return utils.SuccessResponse(ctx, http.StatusOK, "User updated successfully", dto.NewPrivateUserResponse(updated))
 }
 
 func (c *UserController) Delete(ctx echo.Context) error {
@@ -166,9 +233,10 @@ func (c *UserController) Delete(ctx echo.Context) error {
 		return utils.ErrorResponse(ctx, http.StatusBadRequest, "Invalid ID format", err)
 	}
 
-	if err := c.service.Delete(ctx.Request().Context(), parsedID); err != nil {
+	purgeAfter, err := c.accountService.Deactivate(ctx.Request().Context(), parsedID)
+	if err != nil {
 		return utils.ErrorResponse(ctx, http.StatusInternalServerError, "Failed to delete user", err)
 	}
 
-	return utils.SuccessResponse(ctx, http.StatusOK, "User deleted successfully", nil)
+	return utils.SuccessResponse(ctx, http.StatusOK, "User deleted successfully", &dto.DeactivationResponse{PurgeAfter: purgeAfter})
 }
@@ -0,0 +1,28 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// DeactivateAccountRequest defines the structure for deactivating the
+// caller's own account
+type DeactivateAccountRequest struct {
+	Password string `json:"password"` // User's current password, may be omitted by Google users who just signed in
+}
+
+// Validate validates the DeactivateAccountRequest fields
+func (r DeactivateAccountRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Password: optional, checked by the service
+		validation.Field(&r.Password, validation.Length(0, models.MaxPasswordLength)),
+	)
+}
+
+// DeactivationResponse tells when a deactivated account will be purged.
+// Until then an admin can restore it.
+type DeactivationResponse struct {
+	PurgeAfter time.Time `json:"purgeAfter"`
+}
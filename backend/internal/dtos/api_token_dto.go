@@ -0,0 +1,75 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// CreateAPITokenRequest defines the structure for issuing a personal API token
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name"`          // Label shown in the token list
+	Scopes        []string `json:"scopes"`        // Any of read, submit, vote, modposts
+	ExpiresInDays int      `json:"expiresInDays"` // Days until expiry, 0 for a token that never expires
+}
+
+// Validate validates the CreateAPITokenRequest fields
+func (r CreateAPITokenRequest) Validate() error {
+	scopes := make([]interface{}, len(models.APIScopes))
+	for i, scope := range models.APIScopes {
+		scopes[i] = string(scope)
+	}
+	return validation.ValidateStruct(&r,
+		// Name: required, 1-100 characters
+		validation.Field(&r.Name, validation.Required, validation.Length(1, 100)),
+		// Scopes: at least one, each a known scope
+		validation.Field(&r.Scopes, validation.Required, validation.Each(validation.In(scopes...))),
+		// ExpiresInDays: optional, at most a year
+		validation.Field(&r.ExpiresInDays, validation.Min(0), validation.Max(365)),
+	)
+}
+
+// ScopeList returns the requested scopes without duplicates
+func (r CreateAPITokenRequest) ScopeList() []models.APIScope {
+	seen := map[string]bool{}
+	scopes := make([]models.APIScope, 0, len(r.Scopes))
+	for _, scope := range r.Scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, models.APIScope(scope))
+		}
+	}
+	return scopes
+}
+
+// APITokenResponse describes a personal API token without its secret
+type APITokenResponse struct {
+	ID         uuid.UUID         `json:"id"`
+	Name       string            `json:"name"`
+	Prefix     string            `json:"prefix"` // First characters of the token, for recognising it
+	Scopes     []models.APIScope `json:"scopes"`
+	ExpiresAt  *time.Time        `json:"expires_at"`
+	LastUsedAt *time.Time        `json:"last_used_at"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// CreateAPITokenResponse is returned once when a token is issued
+type CreateAPITokenResponse struct {
+	*APITokenResponse
+	Token string `json:"token"` // Plaintext token, shown only this once
+}
+
+// NewAPITokenResponse maps an API token model to its response DTO
+func NewAPITokenResponse(token *models.APIToken) *APITokenResponse {
+	return &APITokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.Scopes,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
@@ -3,6 +3,7 @@ package dtos
 import (
 	"regexp"
 
+	"github.com/dfanso/reddit-clone/internal/models"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
 )
@@ -41,3 +42,177 @@ func (r LoginRequest) Validate() error {
 		validation.Field(&r.Password, validation.Required, validation.Length(8, 72)),
 	)
 }
+
+// TokenResponse carries a freshly issued access/refresh token pair
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`  // Signed ES256 JWT
+	TokenType    string `json:"token_type"`    // Always "Bearer"
+	ExpiresIn    int    `json:"expires_in"`    // Access token lifetime in seconds
+	RefreshToken string `json:"refresh_token"` // Opaque single-use refresh token
+}
+
+// LoginResponse is returned on a successful login. When the user has 2FA
+// enabled the first step only carries MFARequired and MFAToken, which must
+// be exchanged through the MFA login step.
+type LoginResponse struct {
+	*TokenResponse
+	MFARequired bool                 `json:"mfa_required,omitempty"` // Set when a 2FA code is still needed
+	MFAToken    string               `json:"mfa_token,omitempty"`    // Short-lived "mfa_pending" token
+	User        *PrivateUserResponse `json:"user,omitempty"`         // Sanitized user payload
+}
+
+// RefreshRequest defines the structure for exchanging a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"` // Refresh token from login or a previous refresh
+}
+
+// Validate validates the RefreshRequest fields
+func (r RefreshRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// RefreshToken: required, at most 128 characters
+		validation.Field(&r.RefreshToken, validation.Required, validation.Length(1, 128)),
+	)
+}
+
+// VerifyEmailRequest defines the structure for confirming an email with a one-time code
+type VerifyEmailRequest struct {
+	Email string `json:"email"` // User's email address
+	Code  string `json:"code"`  // 6-digit code sent by email
+}
+
+// Validate validates the VerifyEmailRequest fields
+func (r VerifyEmailRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Email: required, valid email format, 5-100 characters
+		validation.Field(&r.Email, validation.Required, validation.Length(5, 100), is.Email),
+		// Code: required, exactly 6 digits
+		validation.Field(&r.Code, validation.Required, validation.Length(6, 6), is.Digit),
+	)
+}
+
+// ResendVerificationRequest defines the structure for requesting a new verification code
+type ResendVerificationRequest struct {
+	Email string `json:"email"` // User's email address
+}
+
+// Validate validates the ResendVerificationRequest fields
+func (r ResendVerificationRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Email: required, valid email format, 5-100 characters
+		validation.Field(&r.Email, validation.Required, validation.Length(5, 100), is.Email),
+	)
+}
+
+// ForgotPasswordRequest defines the structure for requesting a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email"` // User's email address
+}
+
+// Validate validates the ForgotPasswordRequest fields
+func (r ForgotPasswordRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Email: required, valid email format, 5-100 characters
+		validation.Field(&r.Email, validation.Required, validation.Length(5, 100), is.Email),
+	)
+}
+
+// ResetPasswordRequest defines the structure for setting a new password with a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`        // Token from the reset email
+	NewPassword string `json:"new_password"` // User's new password
+}
+
+// Validate validates the ResetPasswordRequest fields
+func (r ResetPasswordRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Token: required, at most 128 characters
+		validation.Field(&r.Token, validation.Required, validation.Length(1, 128)),
+		// NewPassword: required, 8-72 characters
+		validation.Field(&r.NewPassword, validation.Required, validation.Length(models.MinPasswordLength, models.MaxPasswordLength)),
+	)
+}
+
+// CompleteSignupRequest defines the structure for the final signup details
+type CompleteSignupRequest struct {
+	Handler     string `json:"handler"`     // User's public handle, without the "u/" prefix
+	Name        string `json:"name"`        // Display name
+	Avatar      string `json:"avatar"`      // Optional avatar URL
+	Description string `json:"description"` // Optional profile description
+}
+
+// Validate validates the CompleteSignupRequest fields
+func (r CompleteSignupRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Handler: required, 3-20 characters
+		validation.Field(&r.Handler, validation.Required, validation.Length(3, 20), validation.Match(usernameRegex)),
+		// Name: required, 2-50 characters
+		validation.Field(&r.Name, validation.Required, validation.Length(2, 50)),
+		// Avatar: optional URL, at most 255 characters
+		validation.Field(&r.Avatar, validation.Length(0, 255), is.URL),
+		// Description: optional, at most 500 characters
+		validation.Field(&r.Description, validation.Length(0, 500)),
+	)
+}
+
+// MFALoginRequest defines the structure for the second login step
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token"`     // Token from the first login step
+	Code         string `json:"code"`          // 6-digit TOTP code
+	RecoveryCode string `json:"recovery_code"` // Or a one-time recovery code
+}
+
+// Validate validates the MFALoginRequest fields
+func (r MFALoginRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// MFAToken: required
+		validation.Field(&r.MFAToken, validation.Required),
+		// Code: 6 digits, required unless a recovery code is given
+		validation.Field(&r.Code, validation.When(r.RecoveryCode == "", validation.Required), validation.Length(6, 6), is.Digit),
+		// RecoveryCode: at most 32 characters
+		validation.Field(&r.RecoveryCode, validation.Length(0, 32)),
+	)
+}
+
+// MFAEnrollResponse is returned when 2FA enrollment starts
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`      // Base32 secret for manual entry
+	OTPAuthURI string `json:"otpauth_uri"` // otpauth:// URI to render as a QR code
+}
+
+// MFACodeRequest defines the structure for requests confirmed by a TOTP code
+type MFACodeRequest struct {
+	Code string `json:"code"` // 6-digit TOTP code
+}
+
+// Validate validates the MFACodeRequest fields
+func (r MFACodeRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Code: required, exactly 6 digits
+		validation.Field(&r.Code, validation.Required, validation.Length(6, 6), is.Digit),
+	)
+}
+
+// MFADisableRequest defines the structure for turning 2FA off
+type MFADisableRequest struct {
+	Password     string `json:"password"`      // User's current password, may be omitted by Google users who just signed in
+	Code         string `json:"code"`          // 6-digit TOTP code
+	RecoveryCode string `json:"recovery_code"` // Or a one-time recovery code
+}
+
+// Validate validates the MFADisableRequest fields
+func (r MFADisableRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Password: optional, checked by the service
+		validation.Field(&r.Password, validation.Length(0, models.MaxPasswordLength)),
+		// Code: 6 digits, required unless a recovery code is given
+		validation.Field(&r.Code, validation.When(r.RecoveryCode == "", validation.Required), validation.Length(6, 6), is.Digit),
+		// RecoveryCode: at most 32 characters
+		validation.Field(&r.RecoveryCode, validation.Length(0, 32)),
+	)
+}
+
+// RecoveryCodesResponse carries freshly generated recovery codes. They are
+// only ever shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,63 @@
+package dtos
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// CreateBlockRequest defines the structure for blocking a user
+type CreateBlockRequest struct {
+	Handle string `json:"handle"` // Handle of the user to block, with or without the "u/" prefix
+}
+
+// Validate validates the CreateBlockRequest fields
+func (r CreateBlockRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Handle: required, at most 22 characters
+		validation.Field(&r.Handle, validation.Required, validation.Length(1, len(HandlePrefix)+20)),
+	)
+}
+
+// Handler returns the handle without the "u/" prefix
+func (r CreateBlockRequest) Handler() string {
+	return strings.TrimPrefix(r.Handle, HandlePrefix)
+}
+
+// BlockedUserResponse is a user in the caller's block list
+type BlockedUserResponse struct {
+	*PublicUserResponse
+	BlockedAt time.Time `json:"blockedAt"`
+}
+
+// BlockPageResponse is one page of the caller's block list, most recent
+// block first
+type BlockPageResponse struct {
+	Users      []*BlockedUserResponse `json:"users"`
+	NextCursor string                 `json:"nextCursor,omitempty"` // Absent on the last page
+	PrevCursor string                 `json:"prevCursor,omitempty"` // Absent on the first page
+}
+
+// NewBlockPageResponse maps a repository page to its response DTO
+func NewBlockPageResponse(page *pagination.Page[types.BlockListEntry]) *BlockPageResponse {
+	return &BlockPageResponse{
+		Users:      NewBlockedUserResponses(page.Items),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+}
+
+// NewBlockedUserResponses maps repository entries to their response DTOs
+func NewBlockedUserResponses(entries []types.BlockListEntry) []*BlockedUserResponse {
+	users := make([]*BlockedUserResponse, len(entries))
+	for i := range entries {
+		users[i] = &BlockedUserResponse{
+			PublicUserResponse: NewPublicUserResponse(&entries[i].User),
+			BlockedAt:          entries[i].BlockedAt,
+		}
+	}
+	return users
+}
@@ -0,0 +1,32 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/google/uuid"
+)
+
+// DataExportResponse describes one of the caller's data exports
+type DataExportResponse struct {
+	ID          uuid.UUID               `json:"id"`
+	Status      models.DataExportStatus `json:"status"`
+	Size        int64                   `json:"size,omitempty"` // Archive size in bytes once ready
+	CreatedAt   time.Time               `json:"createdAt"`
+	CompletedAt *time.Time              `json:"completedAt"`
+	ExpiresAt   *time.Time              `json:"expiresAt"`             // Set once ready; the archive is deleted after this
+	DownloadURL string                  `json:"downloadUrl,omitempty"` // Signed one-time link, only while ready
+}
+
+// NewDataExportResponse maps a data export model to its response DTO
+func NewDataExportResponse(export *models.DataExport, downloadURL string) *DataExportResponse {
+	return &DataExportResponse{
+		ID:          export.ID,
+		Status:      export.Status,
+		Size:        export.Size,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+		DownloadURL: downloadURL,
+	}
+}
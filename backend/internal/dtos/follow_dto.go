@@ -0,0 +1,43 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+)
+
+// FollowUserResponse is a user in a followers or following list
+type FollowUserResponse struct {
+	*PublicUserResponse
+	FollowedAt time.Time `json:"followedAt"`
+}
+
+// FollowPageResponse is one page of a followers or following list, most
+// recent follow first
+type FollowPageResponse struct {
+	Users      []*FollowUserResponse `json:"users"`
+	NextCursor string                `json:"nextCursor,omitempty"` // Absent on the last page
+	PrevCursor string                `json:"prevCursor,omitempty"` // Absent on the first page
+}
+
+// NewFollowPageResponse maps a repository page to its response DTO
+func NewFollowPageResponse(page *pagination.Page[types.FollowListEntry]) *FollowPageResponse {
+	return &FollowPageResponse{
+		Users:      NewFollowUserResponses(page.Items),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+}
+
+// NewFollowUserResponses maps repository entries to their response DTOs
+func NewFollowUserResponses(entries []types.FollowListEntry) []*FollowUserResponse {
+	users := make([]*FollowUserResponse, len(entries))
+	for i := range entries {
+		users[i] = &FollowUserResponse{
+			PublicUserResponse: NewPublicUserResponse(&entries[i].User),
+			FollowedAt:         entries[i].FollowedAt,
+		}
+	}
+	return users
+}
@@ -0,0 +1,83 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// MaxSuspensionHours caps a suspension at a year; longer is a ban
+const MaxSuspensionHours = 365 * 24
+
+// SuspendUserRequest defines the structure for suspending a user
+type SuspendUserRequest struct {
+	DurationHours int    `json:"durationHours"` // Length of the suspension
+	Reason        string `json:"reason"`        // Why, kept in the audit trail
+}
+
+// Validate validates the SuspendUserRequest fields
+func (r SuspendUserRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// DurationHours: required, 1 hour to a year
+		validation.Field(&r.DurationHours, validation.Required, validation.Min(1), validation.Max(MaxSuspensionHours)),
+		// Reason: required, at most 500 characters
+		validation.Field(&r.Reason, validation.Required, validation.Length(1, 500)),
+	)
+}
+
+// Duration returns the suspension length
+func (r SuspendUserRequest) Duration() time.Duration {
+	return time.Duration(r.DurationHours) * time.Hour
+}
+
+// ModerationReasonRequest defines the body of the ban and unban endpoints
+type ModerationReasonRequest struct {
+	Reason string `json:"reason"` // Why, kept in the audit trail
+}
+
+// Validate validates the ModerationReasonRequest fields
+func (r ModerationReasonRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Reason: required, at most 500 characters
+		validation.Field(&r.Reason, validation.Required, validation.Length(1, 500)),
+	)
+}
+
+// ModerationActionResponse is one entry of a user's moderation history
+type ModerationActionResponse struct {
+	ID             uuid.UUID                   `json:"id"`
+	UserID         uuid.UUID                   `json:"userId"`
+	ActorID        *uuid.UUID                  `json:"actorId"` // Null once the admin's account is gone
+	Action         models.ModerationActionType `json:"action"`
+	Reason         string                      `json:"reason"`
+	ExpiresAt      *time.Time                  `json:"expiresAt,omitempty"` // Set on suspensions
+	PreviousStatus models.Status               `json:"previousStatus"`
+	CreatedAt      time.Time                   `json:"createdAt"`
+}
+
+// NewModerationActionResponse maps a moderation action model to its
+// response DTO
+func NewModerationActionResponse(action *models.ModerationAction) *ModerationActionResponse {
+	return &ModerationActionResponse{
+		ID:             action.ID,
+		UserID:         action.UserID,
+		ActorID:        action.ActorID,
+		Action:         action.Action,
+		Reason:         action.Reason,
+		ExpiresAt:      action.ExpiresAt,
+		PreviousStatus: action.PreviousStatus,
+		CreatedAt:      action.CreatedAt,
+	}
+}
+
+// NewModerationActionResponses maps moderation action models to their
+// response DTOs
+func NewModerationActionResponses(actions []models.ModerationAction) []*ModerationActionResponse {
+	responses := make([]*ModerationActionResponse, len(actions))
+	for i := range actions {
+		responses[i] = NewModerationActionResponse(&actions[i])
+	}
+	return responses
+}
@@ -0,0 +1,63 @@
+package dtos
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// CreateMuteRequest defines the structure for muting a user
+type CreateMuteRequest struct {
+	Handle string `json:"handle"` // Handle of the user to mute, with or without the "u/" prefix
+}
+
+// Validate validates the CreateMuteRequest fields
+func (r CreateMuteRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Handle: required, at most 22 characters
+		validation.Field(&r.Handle, validation.Required, validation.Length(1, len(HandlePrefix)+20)),
+	)
+}
+
+// Handler returns the handle without the "u/" prefix
+func (r CreateMuteRequest) Handler() string {
+	return strings.TrimPrefix(r.Handle, HandlePrefix)
+}
+
+// MutedUserResponse is a user in the caller's mute list
+type MutedUserResponse struct {
+	*PublicUserResponse
+	MutedAt time.Time `json:"mutedAt"`
+}
+
+// MutePageResponse is one page of the caller's mute list, most recent mute
+// first
+type MutePageResponse struct {
+	Users      []*MutedUserResponse `json:"users"`
+	NextCursor string               `json:"nextCursor,omitempty"` // Absent on the last page
+	PrevCursor string               `json:"prevCursor,omitempty"` // Absent on the first page
+}
+
+// NewMutePageResponse maps a repository page to its response DTO
+func NewMutePageResponse(page *pagination.Page[types.MuteListEntry]) *MutePageResponse {
+	return &MutePageResponse{
+		Users:      NewMutedUserResponses(page.Items),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+}
+
+// NewMutedUserResponses maps repository entries to their response DTOs
+func NewMutedUserResponses(entries []types.MuteListEntry) []*MutedUserResponse {
+	users := make([]*MutedUserResponse, len(entries))
+	for i := range entries {
+		users[i] = &MutedUserResponse{
+			PublicUserResponse: NewPublicUserResponse(&entries[i].User),
+			MutedAt:            entries[i].MutedAt,
+		}
+	}
+	return users
+}
@@ -0,0 +1,31 @@
+package dtos
+
+import (
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// PageQuery defines the query parameters of cursor-paginated lists without
+// filters, such as followers and blocks
+type PageQuery struct {
+	Cursor string `query:"cursor"` // nextCursor or prevCursor of an earlier page
+	Limit  int    `query:"limit"`  // Page size, defaults to 20
+}
+
+// Validate validates the PageQuery fields
+func (q PageQuery) Validate() error {
+	return validation.ValidateStruct(&q,
+		// Cursor: optional, at most 512 characters
+		validation.Field(&q.Cursor, validation.Length(0, 512)),
+		// Limit: optional, 1-100
+		validation.Field(&q.Limit, validation.Min(1), validation.Max(pagination.MaxLimit)),
+	)
+}
+
+// Params returns the page selection of the query
+func (q PageQuery) Params() pagination.Params {
+	return pagination.Params{
+		Limit:  q.Limit,
+		Cursor: q.Cursor,
+	}
+}
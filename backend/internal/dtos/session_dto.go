@@ -0,0 +1,36 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/google/uuid"
+)
+
+// ClientInfo describes the device a login comes from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// SessionResponse describes one of the user's signed-in devices
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"` // Set for the session making the request
+}
+
+// NewSessionResponse maps a session model to its response DTO
+func NewSessionResponse(session *models.Session, currentID uuid.UUID) *SessionResponse {
+	return &SessionResponse{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		Current:    session.ID == currentID,
+	}
+}
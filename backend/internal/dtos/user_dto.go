@@ -0,0 +1,349 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/google/uuid"
+)
+
+// HandlePrefix is shown before a user's handler wherever it is displayed
+const HandlePrefix = "u/"
+
+// FormatHandle returns the display form of a handler, e.g. "u/alice"
+func FormatHandle(handler string) string {
+	return HandlePrefix + handler
+}
+
+// PrivateUserResponse is the user payload returned to the user themselves
+// and to admins. Secrets such as the password hash and 2FA secret are
+// never part of it.
+type PrivateUserResponse struct {
+	ID             uuid.UUID     `json:"id"`
+	Handler        string        `json:"handler"` // Bare handler, as sent in requests
+	Handle         string        `json:"handle"`  // Display form with the "u/" prefix
+	Name           string        `json:"name"`
+	Email          string        `json:"email"`
+	Role           models.Role   `json:"role"`
+	Status         models.Status `json:"status"`
+	Stage          models.Stage  `json:"stage"`
+	Avatar         string        `json:"avatar"`
+	Banner         string        `json:"banner"`
+	Description    string        `json:"description"`
+	PostKarma      int           `json:"postKarma"`
+	CommentKarma   int           `json:"commentKarma"`
+	FollowerCount  int           `json:"followerCount"`
+	FollowingCount int           `json:"followingCount"`
+	TOTPEnabled    bool          `json:"totpEnabled"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// NewPrivateUserResponse maps a user model to its private response DTO
+func NewPrivateUserResponse(user *models.User) *PrivateUserResponse {
+	return &PrivateUserResponse{
+		ID:             user.ID,
+		Handler:        user.Handler,
+		Handle:         FormatHandle(user.Handler),
+		Name:           user.Name,
+		Email:          user.Email,
+		Role:           user.Role,
+		Status:         user.Status,
+		Stage:          user.Stage,
+		Avatar:         user.Avatar,
+		Banner:         user.Banner,
+		Description:    user.Description,
+		PostKarma:      user.PostKarma,
+		CommentKarma:   user.CommentKarma,
+		FollowerCount:  user.FollowerCount,
+		FollowingCount: user.FollowingCount,
+		TOTPEnabled:    user.TOTPEnabled,
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
+	}
+}
+
+// NewPrivateUserResponses maps a list of user models to private response DTOs
+func NewPrivateUserResponses(users []models.User) []*PrivateUserResponse {
+	response := make([]*PrivateUserResponse, len(users))
+	for i := range users {
+		response[i] = NewPrivateUserResponse(&users[i])
+	}
+	return response
+}
+
+// PublicUserResponse is the user payload anyone may see. It leaves out the
+// email address, account state and secrets.
+type PublicUserResponse struct {
+	ID             uuid.UUID `json:"id"`
+	Handler        string    `json:"handler"` // Bare handler, as sent in requests
+	Handle         string    `json:"handle"`  // Display form with the "u/" prefix
+	Name           string    `json:"name"`
+	Avatar         string    `json:"avatar"`
+	Banner         string    `json:"banner"`
+	Description    string    `json:"description"`
+	PostKarma      int       `json:"postKarma"`
+	CommentKarma   int       `json:"commentKarma"`
+	Karma          int       `json:"karma"` // Post and comment karma combined
+	FollowerCount  int       `json:"followerCount"`
+	FollowingCount int       `json:"followingCount"`
+	CakeDay        string    `json:"cakeDay"` // Signup date as YYYY-MM-DD (UTC)
+}
+
+// NewPublicUserResponse maps a user model to its public response DTO
+func NewPublicUserResponse(user *models.User) *PublicUserResponse {
+	return &PublicUserResponse{
+		ID:             user.ID,
+		Handler:        user.Handler,
+		Handle:         FormatHandle(user.Handler),
+		Name:           user.Name,
+		Avatar:         user.Avatar,
+		Banner:         user.Banner,
+		Description:    user.Description,
+		PostKarma:      user.PostKarma,
+		CommentKarma:   user.CommentKarma,
+		Karma:          user.PostKarma + user.CommentKarma,
+		FollowerCount:  user.FollowerCount,
+		FollowingCount: user.FollowingCount,
+		CakeDay:        user.CreatedAt.UTC().Format(time.DateOnly),
+	}
+}
+
+// CreateUserRequest defines the structure for an admin creating a user
+type CreateUserRequest struct {
+	Email    string        `json:"email"`    // User's email address
+	Handler  string        `json:"handler"`  // Public handle, without the "u/" prefix
+	Name     string        `json:"name"`     // Display name
+	Password string        `json:"password"` // Initial password
+	Role     models.Role   `json:"role"`     // Defaults to user
+	Status   models.Status `json:"status"`   // Defaults to unverified
+	Stage    models.Stage  `json:"stage"`    // Defaults to email_verification
+}
+
+// Validate validates the CreateUserRequest fields
+func (r CreateUserRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Email: required, valid email format, 5-100 characters
+		validation.Field(&r.Email, validation.Required, validation.Length(5, 100), is.Email),
+		// Handler: required, 3-20 characters
+		validation.Field(&r.Handler, validation.Required, validation.Length(3, 20), validation.Match(usernameRegex)),
+		// Name: required, 2-50 characters
+		validation.Field(&r.Name, validation.Required, validation.Length(2, 50)),
+		// Password: required, 8-72 characters
+		validation.Field(&r.Password, validation.Required, validation.Length(models.MinPasswordLength, models.MaxPasswordLength)),
+		// Role: optional, one of the known roles
+		validation.Field(&r.Role, validation.In(models.RoleAdmin, models.RoleUser)),
+		// Status: optional, verified or unverified; bans go through moderation
+		validation.Field(&r.Status, validation.In(models.StatusVerified, models.StatusUnverified)),
+		// Stage: optional, one of the signup stages
+		validation.Field(&r.Stage, validation.In(models.StageEmailVerification, models.StageEmailVerified, models.StageGoogleSSO, models.StageCompleted)),
+	)
+}
+
+// UpdateProfileRequest defines the profile fields a user may edit. Fields
+// left out of the request body stay unchanged.
+type UpdateProfileRequest struct {
+	Name        *string `json:"name"`        // Display name
+	Description *string `json:"description"` // Profile description, empty to clear
+	Avatar      *string `json:"avatar"`      // Avatar URL, empty to clear
+	Banner      *string `json:"banner"`      // Banner URL, empty to clear
+}
+
+// Validate validates the UpdateProfileRequest fields
+func (r UpdateProfileRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// Name: optional, 2-50 characters when set
+		validation.Field(&r.Name, validation.NilOrNotEmpty, validation.Length(2, 50)),
+		// Description: optional, at most 500 characters
+		validation.Field(&r.Description, validation.Length(0, 500)),
+		// Avatar: optional URL, at most 255 characters
+		validation.Field(&r.Avatar, validation.Length(0, 255), is.URL),
+		// Banner: optional URL, at most 255 characters
+		validation.Field(&r.Banner, validation.Length(0, 255), is.URL),
+	)
+}
+
+// ApplyTo copies the fields set in the request onto user
+func (r UpdateProfileRequest) ApplyTo(user *models.User) {
+	if r.Name != nil {
+		user.Name = *r.Name
+	}
+	if r.Description != nil {
+		user.Description = *r.Description
+	}
+	if r.Avatar != nil {
+		user.Avatar = *r.Avatar
+	}
+	if r.Banner != nil {
+		user.Banner = *r.Banner
+	}
+}
+
+// UpdateUserRequest defines the fields PUT /users/:id accepts: the profile
+// fields, plus role, status, stage and karma which only admins may change
+type UpdateUserRequest struct {
+	UpdateProfileRequest
+	Role         *models.Role   `json:"role"`
+	Status       *models.Status `json:"status"`
+	Stage        *models.Stage  `json:"stage"`
+	PostKarma    *int           `json:"postKarma"`
+	CommentKarma *int           `json:"commentKarma"`
+}
+
+// Validate validates the UpdateUserRequest fields
+func (r UpdateUserRequest) Validate() error {
+	if err := r.UpdateProfileRequest.Validate(); err != nil {
+		return err
+	}
+	return validation.ValidateStruct(&r,
+		// Role: optional, one of the known roles
+		validation.Field(&r.Role, validation.NilOrNotEmpty, validation.In(models.RoleAdmin, models.RoleUser)),
+		// Status: optional, verified or unverified; bans go through moderation
+		validation.Field(&r.Status, validation.NilOrNotEmpty, validation.In(models.StatusVerified, models.StatusUnverified)),
+		// Stage: optional, one of the signup stages
+		validation.Field(&r.Stage, validation.NilOrNotEmpty, validation.In(models.StageEmailVerification, models.StageEmailVerified, models.StageGoogleSSO, models.StageCompleted)),
+	)
+}
+
+// ChangesPrivilegedFields reports whether the request sets role, status,
+// stage or karma to something other than the user's stored value
+func (r UpdateUserRequest) ChangesPrivilegedFields(user *models.User) bool {
+	return (r.Role != nil && *r.Role != user.Role) ||
+		(r.Status != nil && *r.Status != user.Status) ||
+		(r.Stage != nil && *r.Stage != user.Stage) ||
+		(r.PostKarma != nil && *r.PostKarma != user.PostKarma) ||
+		(r.CommentKarma != nil && *r.CommentKarma != user.CommentKarma)
+}
+
+// ApplyPrivilegedTo copies the role, status, stage and karma fields set in
+// the request onto user
+func (r UpdateUserRequest) ApplyPrivilegedTo(user *models.User) {
+	if r.Role != nil {
+		user.Role = *r.Role
+	}
+	if r.Status != nil {
+		user.Status = *r.Status
+	}
+	if r.Stage != nil {
+		user.Stage = *r.Stage
+	}
+	if r.PostKarma != nil {
+		user.PostKarma = *r.PostKarma
+	}
+	if r.CommentKarma != nil {
+		user.CommentKarma = *r.CommentKarma
+	}
+}
+
+// ChangePasswordRequest defines the structure for changing a known password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"` // User's current password
+	NewPassword     string `json:"new_password"`     // Replacement password
+}
+
+// Validate validates the ChangePasswordRequest fields
+func (r ChangePasswordRequest) Validate() error {
+	return validation.ValidateStruct(&r,
+		// CurrentPassword: required
+		validation.Field(&r.CurrentPassword, validation.Required, validation.Length(1, models.MaxPasswordLength)),
+		// NewPassword: required, 8-72 characters, different from the current one
+		validation.Field(&r.NewPassword, validation.Required, validation.Length(models.MinPasswordLength, models.MaxPasswordLength),
+			validation.NotIn(r.CurrentPassword).Error("must differ from the current password")),
+	)
+}
+
+// ListUsersQuery defines the query parameters of the paginated users listing
+type ListUsersQuery struct {
+	Cursor        string `query:"cursor"`         // nextCursor or prevCursor of an earlier page
+	Limit         int    `query:"limit"`          // Page size, defaults to 20
+	IncludeTotal  bool   `query:"include_total"`  // Also return the number of matching users
+	Role          string `query:"role"`           // Only users with this role
+	Status        string `query:"status"`         // Only users with this status
+	Stage         string `query:"stage"`          // Only users at this signup stage
+	CreatedAfter  string `query:"created_after"`  // RFC 3339 time, inclusive
+	CreatedBefore string `query:"created_before"` // RFC 3339 time, exclusive
+	Q             string `query:"q"`              // Prefix of the handler or email
+	Sort          string `query:"sort"`           // created_at, karma or handler
+	Order         string `query:"order"`          // asc or desc
+}
+
+// Validate validates the ListUsersQuery fields
+func (q ListUsersQuery) Validate() error {
+	return validation.ValidateStruct(&q,
+		// Cursor: optional, at most 512 characters
+		validation.Field(&q.Cursor, validation.Length(0, 512)),
+		// Limit: optional, 1-100
+		validation.Field(&q.Limit, validation.Min(1), validation.Max(pagination.MaxLimit)),
+		// Role: optional, one of the known roles
+		validation.Field(&q.Role, validation.In(string(models.RoleAdmin), string(models.RoleUser))),
+		// Status: optional, one of the known statuses
+		validation.Field(&q.Status, validation.In(string(models.StatusVerified), string(models.StatusUnverified), string(models.StatusBanned))),
+		// Stage: optional, one of the signup stages
+		validation.Field(&q.Stage, validation.In(string(models.StageEmailVerification), string(models.StageEmailVerified), string(models.StageGoogleSSO), string(models.StageCompleted))),
+		// CreatedAfter: optional RFC 3339 time
+		validation.Field(&q.CreatedAfter, validation.Date(time.RFC3339)),
+		// CreatedBefore: optional RFC 3339 time
+		validation.Field(&q.CreatedBefore, validation.Date(time.RFC3339)),
+		// Q: optional, at most 100 characters
+		validation.Field(&q.Q, validation.Length(0, 100)),
+		// Sort: optional, one of the sortable fields
+		validation.Field(&q.Sort, validation.In(string(types.UserSortCreatedAt), string(types.UserSortKarma), string(types.UserSortHandler))),
+		// Order: optional, asc or desc
+		validation.Field(&q.Order, validation.In("asc", "desc")),
+	)
+}
+
+// Params returns the page selection of the query
+func (q ListUsersQuery) Params() pagination.Params {
+	return pagination.Params{
+		Limit:        q.Limit,
+		Cursor:       q.Cursor,
+		IncludeTotal: q.IncludeTotal,
+	}
+}
+
+// Filter converts the validated query into a repository filter. Results are
+// newest first unless a sort is given.
+func (q ListUsersQuery) Filter() types.UserFilter {
+	filter := types.UserFilter{
+		Role:   models.Role(q.Role),
+		Status: models.Status(q.Status),
+		Stage:  models.Stage(q.Stage),
+		Search: q.Q,
+		Sort:   types.UserSort(q.Sort),
+		Desc:   q.Order == "desc",
+	}
+	if filter.Sort == "" {
+		filter.Sort = types.UserSortCreatedAt
+		filter.Desc = q.Order != "asc"
+	}
+	if t, err := time.Parse(time.RFC3339, q.CreatedAfter); err == nil {
+		filter.CreatedAfter = &t
+	}
+	if t, err := time.Parse(time.RFC3339, q.CreatedBefore); err == nil {
+		filter.CreatedBefore = &t
+	}
+	return filter
+}
+
+// UserPageResponse is one page of the users listing
+type UserPageResponse struct {
+	Users      []*PrivateUserResponse `json:"users"`
+	NextCursor string                 `json:"nextCursor,omitempty"` // Absent on the last page
+	PrevCursor string                 `json:"prevCursor,omitempty"` // Absent on the first page
+	Total      *int64                 `json:"total,omitempty"`      // Only with include_total
+}
+
+// NewUserPageResponse maps a repository page to its response DTO
+func NewUserPageResponse(page *pagination.Page[models.User]) *UserPageResponse {
+	return &UserPageResponse{
+		Users:      NewPrivateUserResponses(page.Items),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+		Total:      page.Total,
+	}
+}
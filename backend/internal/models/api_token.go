@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APITokenPrefix starts every personal API token so the auth middleware can
+// tell them apart from JWTs and leaked tokens are easy to spot
+const APITokenPrefix = "rcp_"
+
+// APIScope limits what a personal API token may do
+type APIScope string
+
+const (
+	ScopeRead     APIScope = "read"     // Read-only access
+	ScopeSubmit   APIScope = "submit"   // Create posts and comments
+	ScopeVote     APIScope = "vote"     // Vote on posts and comments
+	ScopeModPosts APIScope = "modposts" // Moderate posts
+)
+
+// APIScopes lists every scope a token can be granted
+var APIScopes = []APIScope{ScopeRead, ScopeSubmit, ScopeVote, ScopeModPosts}
+
+// APIToken is a long-lived personal access token for bots and scripts. Only
+// the SHA-256 hash is stored; Prefix keeps the first characters so the user
+// can recognise the token in listings.
+type APIToken struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	User       User       `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Name       string     `json:"name" gorm:"type:varchar(100);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16);not null"`
+	TokenHash  string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Scopes     []APIScope `json:"scopes" gorm:"type:jsonb;serializer:json;not null"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the token can still be used
+func (t *APIToken) IsActive() bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || time.Now().Before(*t.ExpiresAt))
+}
+
+// HasScope reports whether the token was granted scope
+func (t *APIToken) HasScope(scope APIScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
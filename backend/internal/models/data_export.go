@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataExportStatus tracks a data export through the background worker
+type DataExportStatus string
+
+const (
+	ExportPending    DataExportStatus = "pending"    // Waiting for the worker
+	ExportProcessing DataExportStatus = "processing" // Being built
+	ExportReady      DataExportStatus = "ready"      // Stored and downloadable once
+	ExportDownloaded DataExportStatus = "downloaded" // Downloaded and deleted from storage
+	ExportFailed     DataExportStatus = "failed"
+	ExportExpired    DataExportStatus = "expired" // Not downloaded in time and deleted from storage
+)
+
+// DataExport is a user's request for a ZIP of their personal data. The
+// archive lives in the blob store under BlobKey until it is downloaded or
+// expires. A partial unique index allows each user one pending or
+// processing export at a time.
+type DataExport struct {
+	ID           uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID       uuid.UUID        `json:"user_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_data_exports_user_unfinished,where:status = 'pending' OR status = 'processing'"`
+	User         User             `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Status       DataExportStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	BlobKey      string           `json:"-" gorm:"type:varchar(255)"`
+	Size         int64            `json:"size"`
+	Error        string           `json:"-" gorm:"type:text"` // Why building failed, for operators
+	StartedAt    *time.Time       `json:"started_at"`
+	CompletedAt  *time.Time       `json:"completed_at"`
+	ExpiresAt    *time.Time       `json:"expires_at"`
+	DownloadedAt *time.Time       `json:"downloaded_at"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
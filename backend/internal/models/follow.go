@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Follow records that Follower follows Followed. The follower and following
+// counts on User are kept in step with this table.
+type Follow struct {
+	FollowerID uuid.UUID `json:"follower_id" gorm:"type:uuid;primaryKey"`
+	Follower   User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	FollowedID uuid.UUID `json:"followed_id" gorm:"type:uuid;primaryKey;index"`
+	Followed   User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt  time.Time `json:"created_at"`
+}
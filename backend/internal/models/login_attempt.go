@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// LoginAttempt counts recent failed logins for one key, such as an account
+// email or a client IP, and how long that key is locked out for
+type LoginAttempt struct {
+	Key           string     `json:"key" gorm:"type:varchar(320);primary_key"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at" gorm:"not null;index"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
+
+// IsLocked reports whether the key is locked out at now
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ModerationActionType names an admin action taken against a user
+type ModerationActionType string
+
+const (
+	ModerationSuspend ModerationActionType = "suspend"
+	ModerationBan     ModerationActionType = "ban"
+	ModerationUnban   ModerationActionType = "unban" // Lifts a ban or suspension
+)
+
+// ModerationAction is the audit record of a suspension, ban or unban. Rows
+// are only ever added, so the table is the user's moderation history.
+type ModerationAction struct {
+	ID             uuid.UUID            `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID         uuid.UUID            `json:"user_id" gorm:"type:uuid;not null;index"`
+	User           User                 `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	ActorID        *uuid.UUID           `json:"actor_id" gorm:"type:uuid"` // Admin who acted, nil once their account is gone
+	Actor          *User                `json:"-" gorm:"constraint:OnDelete:SET NULL"`
+	Action         ModerationActionType `json:"action" gorm:"type:varchar(20);not null"`
+	Reason         string               `json:"reason" gorm:"type:text;not null"`
+	ExpiresAt      *time.Time           `json:"expires_at"`                                       // End of a suspension
+	PreviousStatus Status               `json:"previous_status" gorm:"type:varchar(20);not null"` // Restored when a ban is lifted
+	CreatedAt      time.Time            `json:"created_at"`
+}
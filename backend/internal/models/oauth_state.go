@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// OAuthState holds the per-attempt secrets of an OAuth2 authorization code
+// flow between the redirect to the provider and its callback. It is keyed
+// by the SHA-256 hash of the state parameter and deleted once used.
+type OAuthState struct {
+	StateHash    string    `json:"-" gorm:"type:char(64);primary_key"`
+	Provider     string    `json:"provider" gorm:"type:varchar(20);not null"`
+	CodeVerifier string    `json:"-" gorm:"not null"`
+	Nonce        string    `json:"-" gorm:"not null"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
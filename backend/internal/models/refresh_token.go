@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is an opaque, single-use token used to obtain new access
+// tokens. Only the SHA-256 hash of the token is stored. Every token issued
+// by rotating another one shares its FamilyID, so a reused token can revoke
+// the whole chain.
+type RefreshToken struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	User      User       `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	FamilyID  uuid.UUID  `json:"family_id" gorm:"type:uuid;not null;index"`
+	TokenHash string     `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the token can still be exchanged
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RotatedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevokedToken blocks a single access token by its jti until the token
+// would have expired anyway
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"type:varchar(64);primary_key"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserTokenRevocation blocks every access token of a user issued before
+// RevokedBefore, which is stored at second precision to match iat. It is kept until the last such token has expired.
+type UserTokenRevocation struct {
+	UserID        uuid.UUID `json:"user_id" gorm:"type:uuid;primary_key"`
+	RevokedBefore time.Time `json:"revoked_before" gorm:"not null"`
+	ExpiresAt     time.Time `json:"expires_at" gorm:"not null;index"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is one signed-in device. Its ID doubles as the refresh token
+// family ID and as the "sid" claim of the access tokens issued for it, so
+// deleting the row signs that device out.
+type Session struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	User       User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserAgent  string    `json:"user_agent" gorm:"type:varchar(255)"`
+	IP         string    `json:"ip" gorm:"type:varchar(45)"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"not null"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -37,10 +39,10 @@ const (
 
 type User struct {
 	ID           uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	Handler      string         `json:"handler" validate:"required,min=3,max=20,matches=^[a-zA-Z0-9]+(_[a-zA-Z0-9]+)*$" gorm:"uniqueIndex;not null"`
+	Handler      string         `json:"handler" validate:"required,min=3,max=20,matches=^[a-zA-Z0-9]+(_[a-zA-Z0-9]+)*$" gorm:"uniqueIndex:idx_users_handler_active,where:deleted_at IS NULL;not null"`
 	Name         string         `json:"name" validate:"required,min=2,max=50" gorm:"not null"`
-	Email        string         `json:"email" validate:"required,email" gorm:"uniqueIndex;not null"`
-	Password     string         `json:"password,omitempty" validate:"required,min=8,max=72" gorm:"not null"`
+	Email        string         `json:"email" validate:"required,email" gorm:"uniqueIndex:idx_users_email_active,where:deleted_at IS NULL;index:idx_users_email_lower,expression:LOWER(email);not null"`
+	Password     string         `json:"-" validate:"required,min=8,max=72" gorm:"not null"`
 	Role         Role           `json:"role" validate:"required,oneof=admin user" gorm:"type:varchar(20);not null;default:'user'"`
 	Status       Status         `json:"status" validate:"required,oneof=verified unverified banned" gorm:"type:varchar(20);not null;default:'unverified'"`
 	Stage        Stage          `json:"stage" validate:"required,oneof=email_verification email_verified google_sso completed" gorm:"type:varchar(20);not null;default:'email_verification'"`
@@ -49,9 +51,56 @@ type User struct {
 	Description  string         `json:"description" gorm:"type:text"`
 	PostKarma    int            `json:"postKarma" gorm:"default:0"`
 	CommentKarma int            `json:"commentKarma" gorm:"default:0"`
+	GoogleID     *string        `json:"-" gorm:"type:varchar(255);uniqueIndex:idx_users_google_id_active,where:deleted_at IS NULL"`
+	TOTPSecret   string         `json:"-" gorm:"type:text"` // AES-GCM encrypted, set once enrollment starts
+	TOTPEnabled  bool           `json:"totpEnabled" gorm:"not null;default:false"`
+	TOTPLastStep int64          `json:"-" gorm:"not null;default:0"` // Last accepted time step, blocks code replay
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"` // Set on deactivation, purged after a grace period
+
+	// Denormalized from the follows table. Read-only for GORM so saving a
+	// user never overwrites a concurrent follow; see FollowRepository.
+	FollowerCount  int `json:"followerCount" gorm:"->;not null;default:0"`
+	FollowingCount int `json:"followingCount" gorm:"->;not null;default:0"`
+
+	// End of a timed suspension, written only by ModerationRepository. The
+	// suspension lifts by itself once this passes; see IsSuspended.
+	SuspendedUntil *time.Time `json:"suspendedUntil,omitempty" gorm:"->"`
+
+	// Set once a deactivated account has been purged. The row stays behind
+	// as an anonymous tombstone so content it authored keeps its author.
+	PurgedAt *time.Time `json:"-" gorm:"->"`
+}
+
+// stageTransitions lists the legal signup stage transitions
+var stageTransitions = map[Stage][]Stage{
+	StageEmailVerification: {StageEmailVerified},
+	StageEmailVerified:     {StageCompleted},
+	StageGoogleSSO:         {StageCompleted},
+}
+
+// TransitionTo moves the user to the next signup stage, rejecting any move
+// the signup flow doesn't allow
+func (u *User) TransitionTo(next Stage) error {
+	for _, allowed := range stageTransitions[u.Stage] {
+		if allowed == next {
+			u.Stage = next
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot move from stage %q to %q", u.Stage, next)
+}
+
+// NormalizeEmail returns email in the form it is stored and looked up in.
+// Addresses are matched case-insensitively.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsSuspended reports whether a suspension is in force at now
+func (u *User) IsSuspended(now time.Time) bool {
+	return u.SuspendedUntil != nil && now.Before(*u.SuspendedUntil)
 }
 
 // Create a singleton validator instance
@@ -98,6 +147,8 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	u.CreatedAt = now
 	u.UpdatedAt = now
 
+	u.Email = NormalizeEmail(u.Email)
+
 	// Set defaults if empty
 	if u.Role == "" {
 		u.Role = RoleUser
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserBlock records that Blocker blocked Blocked. The blocker stops seeing
+// the blocked user's content, and interactions such as following are
+// refused in both directions; see BlockService.
+type UserBlock struct {
+	BlockerID uuid.UUID `json:"blocker_id" gorm:"type:uuid;primaryKey"`
+	Blocker   User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	BlockedID uuid.UUID `json:"blocked_id" gorm:"type:uuid;primaryKey;index"`
+	Blocked   User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserMute records that Muter muted Muted. The muter stops seeing the muted
+// user's content and notifications, but unlike a block nothing else
+// changes: follows stay in place, the muted user can still interact with
+// the muter and is never told; see MuteService.
+type UserMute struct {
+	MuterID   uuid.UUID `json:"muter_id" gorm:"type:uuid;primaryKey"`
+	Muter     User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	MutedID   uuid.UUID `json:"muted_id" gorm:"type:uuid;primaryKey;index"`
+	Muted     User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+// SettingsVersion is the current version of the settings document. Fields
+// added later are filled from the defaults when older documents are read;
+// the version is bumped when an existing field changes meaning.
+const SettingsVersion = 1
+
+// Comment sort orders
+const (
+	CommentSortBest          = "best"
+	CommentSortTop           = "top"
+	CommentSortNew           = "new"
+	CommentSortControversial = "controversial"
+	CommentSortOld           = "old"
+	CommentSortQA            = "qa"
+)
+
+// Who may send a user direct messages
+const (
+	DMPrivacyEveryone  = "everyone"
+	DMPrivacyFollowing = "following" // Only users they follow
+	DMPrivacyNobody    = "nobody"
+)
+
+// Themes
+const (
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+// UserSettings holds a user's preferences as a JSONB document. Users
+// without a row use DefaultSettings.
+type UserSettings struct {
+	UserID    uuid.UUID       `json:"user_id" gorm:"type:uuid;primary_key"`
+	User      User            `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Settings  json.RawMessage `json:"settings" gorm:"type:jsonb;not null"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// SettingsDocument is the schema of UserSettings.Settings
+type SettingsDocument struct {
+	Version            int                        `json:"version"`
+	ShowNSFW           bool                       `json:"showNsfw"`
+	DefaultCommentSort string                     `json:"defaultCommentSort"`
+	EmailNotifications EmailNotificationsSettings `json:"emailNotifications"`
+	DMPrivacy          string                     `json:"dmPrivacy"`
+	Theme              string                     `json:"theme"`
+}
+
+// EmailNotificationsSettings selects which events are sent by email
+type EmailNotificationsSettings struct {
+	Replies        bool `json:"replies"`
+	Mentions       bool `json:"mentions"`
+	DirectMessages bool `json:"directMessages"`
+	NewFollowers   bool `json:"newFollowers"`
+	WeeklyDigest   bool `json:"weeklyDigest"`
+}
+
+// DefaultSettings returns the settings of a user who never changed any
+func DefaultSettings() SettingsDocument {
+	return SettingsDocument{
+		Version:            SettingsVersion,
+		ShowNSFW:           false,
+		DefaultCommentSort: CommentSortBest,
+		EmailNotifications: EmailNotificationsSettings{
+			Replies:        true,
+			Mentions:       true,
+			DirectMessages: true,
+			NewFollowers:   false,
+			WeeklyDigest:   false,
+		},
+		DMPrivacy: DMPrivacyEveryone,
+		Theme:     ThemeSystem,
+	}
+}
+
+// DecodeSettings reads a settings document over the defaults, so fields
+// missing from older documents get their default value. With strict set,
+// unknown fields are an error; stored documents are read leniently so a
+// removed field doesn't break them. The result is stamped with the current
+// version.
+func DecodeSettings(raw []byte, strict bool) (SettingsDocument, error) {
+	doc := DefaultSettings()
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return SettingsDocument{}, err
+	}
+	doc.Version = SettingsVersion
+	return doc, nil
+}
+
+// Validate checks every field holds one of its allowed values
+func (d SettingsDocument) Validate() error {
+	return validation.ValidateStruct(&d,
+		validation.Field(&d.Version, validation.Required, validation.In(SettingsVersion)),
+		validation.Field(&d.DefaultCommentSort, validation.Required, validation.In(
+			CommentSortBest, CommentSortTop, CommentSortNew, CommentSortControversial, CommentSortOld, CommentSortQA)),
+		validation.Field(&d.DMPrivacy, validation.Required, validation.In(DMPrivacyEveryone, DMPrivacyFollowing, DMPrivacyNobody)),
+		validation.Field(&d.Theme, validation.Required, validation.In(ThemeSystem, ThemeLight, ThemeDark)),
+	)
+}
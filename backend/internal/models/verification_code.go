@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VerificationCode is the pending email verification one-time code of a
+// user. Only the bcrypt hash of the code is stored, and a user has at most
+// one code at a time.
+type VerificationCode struct {
+	ID              uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID          uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex"`
+	User            User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	CodeHash        string    `json:"-" gorm:"not null"`
+	ExpiresAt       time.Time `json:"expires_at" gorm:"not null"`
+	Attempts        int       `json:"attempts" gorm:"not null;default:0"`
+	SentAt          time.Time `json:"sent_at" gorm:"not null"`
+	SendCount       int       `json:"send_count" gorm:"not null;default:0"`
+	WindowStartedAt time.Time `json:"window_started_at" gorm:"not null"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"net/http"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// IsAdmin reports whether the user has the admin role
+func IsAdmin(user *models.User) bool {
+	return user != nil && user.Role == models.RoleAdmin
+}
+
+// CanManageUser reports whether actor may act on the user with targetID
+func CanManageUser(actor *models.User, targetID string) bool {
+	if actor == nil {
+		return false
+	}
+	return IsAdmin(actor) || actor.ID.String() == targetID
+}
+
+// CanChangePrivilegedFields reports whether actor may change role, status,
+// stage or karma on a user
+func CanChangePrivilegedFields(actor *models.User) bool {
+	return IsAdmin(actor)
+}
+
+// RequireRole only lets users with one of the given roles through.
+// It must be mounted after the auth middleware.
+func RequireRole(roles ...models.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, ok := middleware.UserFromContext(c)
+			if !ok {
+				return utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication required", nil)
+			}
+			for _, role := range roles {
+				if user.Role == role {
+					return next(c)
+				}
+			}
+			return utils.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions", nil)
+		}
+	}
+}
+
+// RequireSelfOrAdmin only lets the request through when the path parameter
+// param holds the caller's own user ID, or the caller is an admin.
+// It must be mounted after the auth middleware.
+func RequireSelfOrAdmin(param string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, ok := middleware.UserFromContext(c)
+			if !ok {
+				return utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication required", nil)
+			}
+			if !CanManageUser(user, c.Param(param)) {
+				return utils.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions", nil)
+			}
+			return next(c)
+		}
+	}
+}
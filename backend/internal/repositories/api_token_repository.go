@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type APITokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) *APITokenRepository {
+	return &APITokenRepository{
+		db: db,
+	}
+}
+
+func (r *APITokenRepository) Create(ctx context.Context, token *models.APIToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// FindByHash returns the token with the given hash, or nil if none exists
+func (r *APITokenRepository) FindByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// FindByUserID lists the user's unrevoked tokens, newest first
+func (r *APITokenRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// CountActive counts the user's unrevoked, unexpired tokens
+func (r *APITokenRepository) CountActive(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+// Revoke revokes one of the user's tokens and reports whether it was active
+func (r *APITokenRepository) Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeAll revokes every active token of the user
+func (r *APITokenRepository) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// TouchLastUsed records a use of the token, writing at most once per
+// interval so busy bots don't cost a write per request
+func (r *APITokenRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, interval time.Duration) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&models.APIToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-interval)).
+		Update("last_used_at", now).Error
+}
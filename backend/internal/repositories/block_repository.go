@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BlockRepository struct {
+	db      *gorm.DB
+	cursors *pagination.Signer
+}
+
+func NewBlockRepository(db *gorm.DB, cursors *pagination.Signer) *BlockRepository {
+	return &BlockRepository{
+		db:      db,
+		cursors: cursors,
+	}
+}
+
+// NotBlockedBy returns a scope leaving out rows whose author, in the trusted
+// SQL column authorColumn, was blocked by viewerID. Feeds, comment trees and
+// notifications use it so filtering happens in SQL, backed by the
+// (blocker_id, blocked_id) primary key:
+//
+//	db.Scopes(repositories.NotBlockedBy(viewer.ID, "posts.author_id"))
+//
+// Nothing calls it yet: it is for the post and comment listings, which
+// don't exist in this tree.
+func NotBlockedBy(viewerID uuid.UUID, authorColumn string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("NOT EXISTS (SELECT 1 FROM user_blocks WHERE user_blocks.blocker_id = ? AND user_blocks.blocked_id = "+authorColumn+")", viewerID)
+	}
+}
+
+// Create records the block and drops any follow between the two users, in
+// either direction, in the same transaction. It holds the pair lock
+// FollowRepository.Create takes, so no follow is created concurrently. It reports false when the
+// block already existed.
+func (r *BlockRepository) Create(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error) {
+	created := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUserPair(tx, blockerID, blockedID); err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.UserBlock{BlockerID: blockerID, BlockedID: blockedID})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		created = true
+
+		for _, pair := range [][2]uuid.UUID{{blockerID, blockedID}, {blockedID, blockerID}} {
+			result := tx.Where("follower_id = ? AND followed_id = ?", pair[0], pair[1]).Delete(&models.Follow{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				if err := adjustFollowCounts(tx, pair[0], pair[1], -1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return created, err
+}
+
+// Delete removes the block. It reports false when there was nothing to
+// remove.
+func (r *BlockRepository) Delete(ctx context.Context, blockerID, blockedID uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Delete(&models.UserBlock{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ExistsBetween reports whether either user has blocked the other
+func (r *BlockRepository) ExistsBetween(ctx context.Context, a, b uuid.UUID) (bool, error) {
+	return blockExistsBetween(r.db.WithContext(ctx), a, b)
+}
+
+func blockExistsBetween(db *gorm.DB, a, b uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.UserBlock{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", a, b, b, a).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindPage returns one page of the users blockerID blocked, most recent
+// first
+func (r *BlockRepository) FindPage(ctx context.Context, blockerID uuid.UUID, params pagination.Params) (*pagination.Page[types.BlockListEntry], error) {
+	db := r.listQuery(ctx, blockerID)
+	order := pagination.Order{Column: "user_blocks.created_at", Desc: true, IDColumn: "user_blocks.blocked_id"}
+	return pagination.Paginate(db, r.cursors, order, params, func(e *types.BlockListEntry) (time.Time, uuid.UUID) {
+		return e.BlockedAt, e.ID
+	})
+}
+
+// FindAll returns every user blockerID blocked, oldest block first
+func (r *BlockRepository) FindAll(ctx context.Context, blockerID uuid.UUID) ([]types.BlockListEntry, error) {
+	var entries []types.BlockListEntry
+	err := r.listQuery(ctx, blockerID).Order("user_blocks.created_at").Find(&entries).Error
+	return entries, err
+}
+
+// listQuery selects the users blockerID blocked along with when each block
+// was made
+func (r *BlockRepository) listQuery(ctx context.Context, blockerID uuid.UUID) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Table("users").
+		Select("users.*, user_blocks.created_at AS blocked_at").
+		Joins("JOIN user_blocks ON users.id = user_blocks.blocked_id").
+		Where("user_blocks.blocker_id = ?", blockerID)
+}
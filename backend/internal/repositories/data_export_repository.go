@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DataExportRepository struct {
+	db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) *DataExportRepository {
+	return &DataExportRepository{
+		db: db,
+	}
+}
+
+func (r *DataExportRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := r.db.WithContext(ctx).First(&export, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FindByUserID returns the user's most recent exports, newest first
+func (r *DataExportRepository) FindByUserID(ctx context.Context, userID uuid.UUID, limit int) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&exports).Error
+	return exports, err
+}
+
+// CreateWithinLimit inserts the export unless the user already has one
+// pending or processing, or has requested limit exports that didn't fail
+// since the given time. The user's row is locked while counting, so
+// concurrent requests are checked one after another, and the partial
+// unique index on unfinished exports backs the first rule. It reports
+// whether the export was created and, when the limit was reached, the
+// counted exports, oldest first.
+func (r *DataExportRepository) CreateWithinLimit(ctx context.Context, export *models.DataExport, since time.Time, limit int) (created bool, counted []models.DataExport, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT 1 FROM users WHERE id = ? FOR UPDATE", export.UserID).Error; err != nil {
+			return err
+		}
+
+		var recent []models.DataExport
+		err := tx.Where("user_id = ? AND created_at > ? AND status <> ?", export.UserID, since, models.ExportFailed).
+			Order("created_at").
+			Find(&recent).Error
+		if err != nil {
+			return err
+		}
+		if len(recent) >= limit {
+			counted = recent
+			return nil
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(export)
+		created = result.RowsAffected > 0
+		return result.Error
+	})
+	return created, counted, err
+}
+
+// ClaimNext marks the oldest pending export as processing and returns it,
+// or nil when there is none. Exports stuck in processing since before
+// staleBefore, left by a worker that died, are claimed again. SKIP LOCKED
+// lets several instances run workers without building an export twice.
+func (r *DataExportRepository) ClaimNext(ctx context.Context, staleBefore time.Time) (*models.DataExport, error) {
+	var claimed *models.DataExport
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var export models.DataExport
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND started_at < ?)", models.ExportPending, models.ExportProcessing, staleBefore).
+			Order("created_at").
+			First(&export).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		now := time.Now()
+		err = tx.Model(&export).Updates(map[string]any{"status": models.ExportProcessing, "started_at": now}).Error
+		if err != nil {
+			return err
+		}
+		claimed = &export
+		return nil
+	})
+	return claimed, err
+}
+
+// MarkReady records the stored archive of an export being processed
+func (r *DataExportRepository) MarkReady(ctx context.Context, id uuid.UUID, blobKey string, size int64, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.DataExport{}).
+		Where("id = ? AND status = ?", id, models.ExportProcessing).
+		Updates(map[string]any{
+			"status":       models.ExportReady,
+			"blob_key":     blobKey,
+			"size":         size,
+			"completed_at": time.Now(),
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// MarkFailed records why an export could not be built
+func (r *DataExportRepository) MarkFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	return r.db.WithContext(ctx).Model(&models.DataExport{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"status": models.ExportFailed, "error": reason, "completed_at": time.Now()}).Error
+}
+
+// ClaimDownload marks a ready, unexpired export as downloaded and returns
+// it, or nil if it was already downloaded, expired or never finished. Only
+// one caller can claim an export.
+func (r *DataExportRepository) ClaimDownload(ctx context.Context, id uuid.UUID) (*models.DataExport, error) {
+	var export models.DataExport
+	result := r.db.WithContext(ctx).Model(&export).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ? AND expires_at > ?", id, models.ExportReady, time.Now()).
+		Updates(map[string]any{"status": models.ExportDownloaded, "downloaded_at": time.Now()})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &export, nil
+}
+
+// FindExpired returns up to limit ready exports that expired before now
+func (r *DataExportRepository) FindExpired(ctx context.Context, now time.Time, limit int) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", models.ExportReady, now).
+		Limit(limit).
+		Find(&exports).Error
+	return exports, err
+}
+
+// MarkExpired records that an expired export was removed from storage
+func (r *DataExportRepository) MarkExpired(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.DataExport{}).
+		Where("id = ? AND status = ?", id, models.ExportReady).
+		Update("status", models.ExportExpired).Error
+}
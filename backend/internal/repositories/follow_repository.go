@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type FollowRepository struct {
+	db      *gorm.DB
+	cursors *pagination.Signer
+}
+
+func NewFollowRepository(db *gorm.DB, cursors *pagination.Signer) *FollowRepository {
+	return &FollowRepository{
+		db:      db,
+		cursors: cursors,
+	}
+}
+
+// Create makes followerID follow followedID and bumps both users' counts in
+// the same transaction. The follow is only inserted when neither user has
+// blocked the other, and the check runs in the insert itself so a block
+// committed first is always seen. It reports whether the follow was
+// created and, when it wasn't, whether a block prevented it.
+func (r *FollowRepository) Create(ctx context.Context, followerID, followedID uuid.UUID) (created, blocked bool, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUserPair(tx, followerID, followedID); err != nil {
+			return err
+		}
+		result := tx.Exec(`INSERT INTO follows (follower_id, followed_id, created_at)
+			SELECT ?, ?, ?
+			WHERE NOT EXISTS (
+				SELECT 1 FROM user_blocks
+				WHERE (blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)
+			)
+			ON CONFLICT DO NOTHING`,
+			followerID, followedID, time.Now(),
+			followerID, followedID, followedID, followerID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			blocked, err = blockExistsBetween(tx, followerID, followedID)
+			return err
+		}
+		created = true
+		return adjustFollowCounts(tx, followerID, followedID, 1)
+	})
+	return created, blocked, err
+}
+
+// Delete removes the follow and lowers both users' counts in the same
+// transaction. It reports false when there was nothing to remove.
+func (r *FollowRepository) Delete(ctx context.Context, followerID, followedID uuid.UUID) (bool, error) {
+	deleted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("follower_id = ? AND followed_id = ?", followerID, followedID).Delete(&models.Follow{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+		return adjustFollowCounts(tx, followerID, followedID, -1)
+	})
+	return deleted, err
+}
+
+// lockUserPair locks both users' rows, in id order so two transactions on
+// the same pair can't deadlock. Follows and blocks between a pair take it
+// first, so a follow can't slip in while a block is removing follows.
+func lockUserPair(tx *gorm.DB, a, b uuid.UUID) error {
+	return tx.Exec("SELECT 1 FROM users WHERE id IN (?, ?) ORDER BY id FOR UPDATE", a, b).Error
+}
+
+// adjustFollowCounts adds delta to the follower's following count and the
+// followed user's follower count. Both rows change in one statement so two
+// users following each other at once can't deadlock.
+func adjustFollowCounts(tx *gorm.DB, followerID, followedID uuid.UUID, delta int) error {
+	return tx.Exec(`UPDATE users SET
+		following_count = following_count + CASE WHEN id = ? THEN ? ELSE 0 END,
+		follower_count = follower_count + CASE WHEN id = ? THEN ? ELSE 0 END
+		WHERE id IN (?, ?)`,
+		followerID, delta, followedID, delta, followerID, followedID).Error
+}
+
+// FindFollowersPage returns one page of the users following userID, most
+// recent follow first
+func (r *FollowRepository) FindFollowersPage(ctx context.Context, userID uuid.UUID, params pagination.Params) (*pagination.Page[types.FollowListEntry], error) {
+	return r.findPage(ctx, "follows.follower_id", "follows.followed_id", userID, params)
+}
+
+// FindFollowingPage returns one page of the users userID follows, most
+// recent follow first
+func (r *FollowRepository) FindFollowingPage(ctx context.Context, userID uuid.UUID, params pagination.Params) (*pagination.Page[types.FollowListEntry], error) {
+	return r.findPage(ctx, "follows.followed_id", "follows.follower_id", userID, params)
+}
+
+// FindAllFollowers returns every user following userID, oldest follow
+// first. Used for data exports, so users hidden from public lists are kept.
+func (r *FollowRepository) FindAllFollowers(ctx context.Context, userID uuid.UUID) ([]types.FollowListEntry, error) {
+	var entries []types.FollowListEntry
+	err := r.listQuery(ctx, "follows.follower_id", "follows.followed_id", userID).
+		Order("follows.created_at").
+		Find(&entries).Error
+	return entries, err
+}
+
+// FindAllFollowing returns every user userID follows, oldest follow first
+func (r *FollowRepository) FindAllFollowing(ctx context.Context, userID uuid.UUID) ([]types.FollowListEntry, error) {
+	var entries []types.FollowListEntry
+	err := r.listQuery(ctx, "follows.followed_id", "follows.follower_id", userID).
+		Order("follows.created_at").
+		Find(&entries).Error
+	return entries, err
+}
+
+// findPage lists the users on the listColumn side of userID's follows.
+// Users who can't be shown publicly are left out.
+func (r *FollowRepository) findPage(ctx context.Context, listColumn, ownerColumn string, userID uuid.UUID, params pagination.Params) (*pagination.Page[types.FollowListEntry], error) {
+	db := r.listQuery(ctx, listColumn, ownerColumn, userID).
+		Where("users.stage = ? AND users.status <> ?", models.StageCompleted, models.StatusBanned)
+	order := pagination.Order{Column: "follows.created_at", Desc: true, IDColumn: listColumn}
+	return pagination.Paginate(db, r.cursors, order, params, func(e *types.FollowListEntry) (time.Time, uuid.UUID) {
+		return e.FollowedAt, e.ID
+	})
+}
+
+// listQuery selects the users on the listColumn side of userID's follows
+// along with when each follow was made
+func (r *FollowRepository) listQuery(ctx context.Context, listColumn, ownerColumn string, userID uuid.UUID) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Table("users").
+		Select("users.*, follows.created_at AS followed_at").
+		Joins("JOIN follows ON users.id = "+listColumn).
+		Where(ownerColumn+" = ?", userID)
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptRepository is the Postgres-backed login attempt store, shared
+// by every server instance
+type LoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		db: db,
+	}
+}
+
+// Get returns the attempts recorded for key, or nil if there are none
+func (r *LoginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	result := r.db.WithContext(ctx).First(&attempt, "key = ?", key)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &attempt, nil
+}
+
+// RecordFailure atomically counts a failure for key. The count starts over
+// when the previous failure is older than window.
+func (r *LoginAttemptRepository) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	attempt := models.LoginAttempt{
+		Key:           key,
+		Failures:      1,
+		LastFailureAt: now,
+	}
+	err := r.db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "failures"}, Value: gorm.Expr(
+					"CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END", now.Add(-window))},
+				{Column: clause.Column{Name: "last_failure_at"}, Value: now},
+			},
+		},
+		clause.Returning{},
+	).Create(&attempt).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *LoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.LoginAttempt{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (r *LoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	return r.db.WithContext(ctx).Delete(&models.LoginAttempt{}, "key = ?", key).Error
+}
+
+// PruneStale deletes keys whose last failure is older than window and that
+// are not locked out, and returns how many were deleted
+func (r *LoginAttemptRepository) PruneStale(ctx context.Context, now time.Time, window time.Duration) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until <= ?)", now.Add(-window), now).
+		Delete(&models.LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+)
+
+// memorySweepEvery controls how often stale entries are dropped
+const memorySweepEvery = 1000
+
+// MemoryLoginAttemptStore keeps login attempts in process memory. It suits
+// a single server instance and tests; counts are lost on restart.
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+	writes   int
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		attempts: map[string]models.LoginAttempt{},
+	}
+}
+
+func (s *MemoryLoginAttemptStore) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writes++
+	if s.writes%memorySweepEvery == 0 {
+		s.sweep(now, window)
+	}
+
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.LastFailureAt.Before(now.Add(-window)) {
+		attempt = models.LoginAttempt{Key: key, LockedUntil: attempt.LockedUntil}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		attempt.LockedUntil = &until
+		s.attempts[key] = attempt
+	}
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) PruneStale(ctx context.Context, now time.Time, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.attempts)
+	s.sweep(now, window)
+	return int64(before - len(s.attempts)), nil
+}
+
+// sweep drops entries that are neither recent nor locked. It must be
+// called with mu held.
+func (s *MemoryLoginAttemptStore) sweep(now time.Time, window time.Duration) {
+	for key, attempt := range s.attempts {
+		if attempt.LastFailureAt.Before(now.Add(-window)) && !attempt.IsLocked(now) {
+			delete(s.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ModerationRepository struct {
+	db *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) *ModerationRepository {
+	return &ModerationRepository{
+		db: db,
+	}
+}
+
+// Apply writes the moderation columns in updates to the user and records
+// the action in the same transaction, so every change has an audit row.
+// Only the named columns are written, leaving the rest of the user alone.
+// The update goes through the table rather than the model because GORM
+// never writes read-only fields such as User.SuspendedUntil.
+func (r *ModerationRepository) Apply(ctx context.Context, updates map[string]any, action *models.ModerationAction) error {
+	updates["updated_at"] = time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Table("users").Where("id = ? AND deleted_at IS NULL", action.UserID).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(action).Error
+	})
+}
+
+// FindLatest returns the user's most recent action of the given type, or
+// nil if there is none
+func (r *ModerationRepository) FindLatest(ctx context.Context, userID uuid.UUID, actionType models.ModerationActionType) (*models.ModerationAction, error) {
+	var action models.ModerationAction
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND action = ?", userID, actionType).
+		Order("created_at DESC").
+		First(&action).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &action, nil
+}
+
+// FindByUserID returns the user's moderation history, newest first
+func (r *ModerationRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]models.ModerationAction, error) {
+	var actions []models.ModerationAction
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&actions).Error
+	return actions, err
+}
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MuteRepository struct {
+	db      *gorm.DB
+	cursors *pagination.Signer
+}
+
+func NewMuteRepository(db *gorm.DB, cursors *pagination.Signer) *MuteRepository {
+	return &MuteRepository{
+		db:      db,
+		cursors: cursors,
+	}
+}
+
+// NotMutedBy returns a scope leaving out rows whose author, in the trusted
+// SQL column authorColumn, was muted by viewerID. It is used alongside
+// NotBlockedBy wherever content is listed for a viewer:
+//
+//	db.Scopes(repositories.NotBlockedBy(viewer.ID, "posts.author_id"),
+//		repositories.NotMutedBy(viewer.ID, "posts.author_id"))
+//
+// Like NotBlockedBy it waits for the post and comment listings, so nothing
+// calls it yet.
+func NotMutedBy(viewerID uuid.UUID, authorColumn string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("NOT EXISTS (SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = ? AND user_mutes.muted_id = "+authorColumn+")", viewerID)
+	}
+}
+
+// Create records the mute. It reports false when the mute already existed.
+func (r *MuteRepository) Create(ctx context.Context, muterID, mutedID uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.UserMute{MuterID: muterID, MutedID: mutedID})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Delete removes the mute. It reports false when there was nothing to
+// remove.
+func (r *MuteRepository) Delete(ctx context.Context, muterID, mutedID uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("muter_id = ? AND muted_id = ?", muterID, mutedID).
+		Delete(&models.UserMute{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindPage returns one page of the users muterID muted, most recent first
+func (r *MuteRepository) FindPage(ctx context.Context, muterID uuid.UUID, params pagination.Params) (*pagination.Page[types.MuteListEntry], error) {
+	db := r.listQuery(ctx, muterID)
+	order := pagination.Order{Column: "user_mutes.created_at", Desc: true, IDColumn: "user_mutes.muted_id"}
+	return pagination.Paginate(db, r.cursors, order, params, func(e *types.MuteListEntry) (time.Time, uuid.UUID) {
+		return e.MutedAt, e.ID
+	})
+}
+
+// FindAll returns every user muterID muted, oldest mute first
+func (r *MuteRepository) FindAll(ctx context.Context, muterID uuid.UUID) ([]types.MuteListEntry, error) {
+	var entries []types.MuteListEntry
+	err := r.listQuery(ctx, muterID).Order("user_mutes.created_at").Find(&entries).Error
+	return entries, err
+}
+
+// listQuery selects the users muterID muted along with when each mute was
+// made
+func (r *MuteRepository) listQuery(ctx context.Context, muterID uuid.UUID) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Table("users").
+		Select("users.*, user_mutes.created_at AS muted_at").
+		Joins("JOIN user_mutes ON users.id = user_mutes.muted_id").
+		Where("user_mutes.muter_id = ?", muterID)
+}
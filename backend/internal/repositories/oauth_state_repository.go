@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OAuthStateRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthStateRepository(db *gorm.DB) *OAuthStateRepository {
+	return &OAuthStateRepository{
+		db: db,
+	}
+}
+
+func (r *OAuthStateRepository) Create(ctx context.Context, state *models.OAuthState) error {
+	return r.db.WithContext(ctx).Create(state).Error
+}
+
+// Consume deletes and returns the unexpired state with the given hash, or
+// nil if there is none. A state can therefore only be used once.
+func (r *OAuthStateRepository) Consume(ctx context.Context, stateHash string) (*models.OAuthState, error) {
+	var states []models.OAuthState
+	result := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("state_hash = ? AND expires_at > ?", stateHash, time.Now()).
+		Delete(&states)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+	return &states[0], nil
+}
+
+// DeleteExpired removes abandoned flows
+func (r *OAuthStateRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.OAuthState{}).Error
+}
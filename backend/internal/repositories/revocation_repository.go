@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevocationRepository struct {
+	db *gorm.DB
+}
+
+func NewRevocationRepository(db *gorm.DB) *RevocationRepository {
+	return &RevocationRepository{
+		db: db,
+	}
+}
+
+// RevokeToken stores a revoked jti, ignoring duplicates
+func (r *RevocationRepository) RevokeToken(ctx context.Context, token *models.RevokedToken) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+func (r *RevocationRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+// RevokeUserTokens upserts the user's revocation cutoff
+func (r *RevocationRepository) RevokeUserTokens(ctx context.Context, revocation *models.UserTokenRevocation) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "expires_at"}),
+	}).Create(revocation).Error
+}
+
+// FindUserRevocation returns the user's revocation cutoff, or nil if none exists
+func (r *RevocationRepository) FindUserRevocation(ctx context.Context, userID uuid.UUID) (*models.UserTokenRevocation, error) {
+	var revocation models.UserTokenRevocation
+	result := r.db.WithContext(ctx).First(&revocation, "user_id = ?", userID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &revocation, nil
+}
+
+// PruneExpired deletes revocation entries whose tokens have all expired
+func (r *RevocationRepository) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	var pruned int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("expires_at <= ?", now).Delete(&models.RevokedToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		pruned += result.RowsAffected
+
+		result = tx.Where("expires_at <= ?", now).Delete(&models.UserTokenRevocation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		pruned += result.RowsAffected
+		return nil
+	})
+	return pruned, err
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *SessionRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+// FindByID returns one of the user's sessions, or nil if none exists
+func (r *SessionRepository) FindByID(ctx context.Context, userID, id uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindByUserID lists the user's sessions, most recently active first
+func (r *SessionRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// Delete removes one of the user's sessions and reports whether it existed
+func (r *SessionRepository) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.Session{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *SessionRepository) DeleteAllForUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Session{}).Error
+}
+
+// DeleteAllForUserExcept removes every session of the user but one
+func (r *SessionRepository) DeleteAllForUserExcept(ctx context.Context, userID, keepID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND id <> ?", userID, keepID).Delete(&models.Session{}).Error
+}
+
+// UpdateLastSeen writes a batch of last-seen times in one transaction.
+// Times never move backwards.
+func (r *SessionRepository) UpdateLastSeen(ctx context.Context, lastSeen map[uuid.UUID]time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, seenAt := range lastSeen {
+			err := tx.Model(&models.Session{}).
+				Where("id = ? AND last_seen_at < ?", id, seenAt).
+				Update("last_seen_at", seenAt).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
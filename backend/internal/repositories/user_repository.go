@@ -3,22 +3,28 @@ package repositories
 import (
 	"context"
 	"errors"
-	"math"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/dfanso/reddit-clone/internal/models"
 	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
-	db *gorm.DB
-	types.UserPaginationResult
+	db      *gorm.DB
+	cursors *pagination.Signer
 }
 
-func NewUserRepository(db *gorm.DB) *UserRepository {
+func NewUserRepository(db *gorm.DB, cursors *pagination.Signer) *UserRepository {
 	return &UserRepository{
-		db: db,
+		db:      db,
+		cursors: cursors,
 	}
 }
 
@@ -34,68 +40,77 @@ func (r *UserRepository) FindOne(ctx context.Context, query interface{}) (*model
 	return &user, nil
 }
 
+// FindByEmail returns the user with the email, ignoring case, or nil if
+// there is none
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.FindOne(ctx, clause.Expr{SQL: "LOWER(email) = ?", Vars: []any{models.NormalizeEmail(email)}})
+}
+
 func (r *UserRepository) FindAll(ctx context.Context) ([]models.User, error) {
 	var users []models.User
 	result := r.db.WithContext(ctx).Find(&users)
-	for i := range users {
-		users[i].Password = ""
-	}
 	return users, result.Error
 }
 
-func (r *UserRepository) FindPaginated(ctx context.Context, query interface{}, page int, limit int) (*types.UserPaginationResult, error) {
+// userSortColumns maps the sortable fields to trusted SQL expressions
+var userSortColumns = map[types.UserSort]string{
+	types.UserSortCreatedAt: "created_at",
+	types.UserSortKarma:     "post_karma + comment_karma",
+	types.UserSortHandler:   "handler",
+}
+
+// likeEscaper escapes the LIKE wildcards in user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
 
-	// Validate page (minimum 1)
-	if page < 1 {
-		page = 1
-	}
-	// Validate limit (default to 10, max 100)
-	if limit < 1 {
-		limit = 10
+// FindPage returns one page of the users matching the filter, walking
+// from the position in params.Cursor
+func (r *UserRepository) FindPage(ctx context.Context, filter types.UserFilter, params pagination.Params) (*pagination.Page[models.User], error) {
+	db := applyUserFilter(r.db.WithContext(ctx), filter)
+	column, ok := userSortColumns[filter.Sort]
+	if !ok {
+		filter.Sort, column = types.UserSortCreatedAt, userSortColumns[types.UserSortCreatedAt]
 	}
-	if limit > 100 {
-		limit = 100
+	order := pagination.Order{Column: column, Desc: filter.Desc}
+
+	// The cursor key type follows the sort column
+	switch filter.Sort {
+	case types.UserSortKarma:
+		return pagination.Paginate(db, r.cursors, order, params, func(u *models.User) (int, uuid.UUID) {
+			return u.PostKarma + u.CommentKarma, u.ID
+		})
+	case types.UserSortHandler:
+		return pagination.Paginate(db, r.cursors, order, params, func(u *models.User) (string, uuid.UUID) {
+			return u.Handler, u.ID
+		})
+	default:
+		return pagination.Paginate(db, r.cursors, order, params, func(u *models.User) (time.Time, uuid.UUID) {
+			return u.CreatedAt, u.ID
+		})
 	}
+}
 
-	// Get total count of users matching the query
-	var total int64
-	db := r.db.WithContext(ctx).Model(&models.User{})
-	if query != nil {
-		db = db.Where(query) // Apply filter if provided
+// applyUserFilter adds the WHERE clauses for the set filter fields
+func applyUserFilter(db *gorm.DB, filter types.UserFilter) *gorm.DB {
+	if filter.Role != "" {
+		db = db.Where("role = ?", filter.Role)
 	}
-	err := db.Count(&total).Error
-	if err != nil {
-		return nil, err
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
 	}
-
-	// Fetch paginated users
-	var users []models.User
-	offset := (page - 1) * limit // Calculate offset
-	db = r.db.WithContext(ctx).Offset(offset).Limit(limit)
-	if query != nil {
-		db = db.Where(query) // Apply filter to the main query
+	if filter.Stage != "" {
+		db = db.Where("stage = ?", filter.Stage)
 	}
-	err = db.Find(&users).Error
-	if err != nil {
-		return nil, err
+	if filter.CreatedAfter != nil {
+		db = db.Where("created_at >= ?", *filter.CreatedAfter)
 	}
-
-	//TODO: this is used to remove the password from the response, have to change with the qurry
-	for i := range users {
-		users[i].Password = ""
+	if filter.CreatedBefore != nil {
+		db = db.Where("created_at < ?", *filter.CreatedBefore)
 	}
-
-	// Calculate total pages
-	totalPages := int(math.Ceil(float64(total) / float64(limit)))
-
-	// Return the result
-	return &types.UserPaginationResult{
-		Users:      users,
-		Total:      total,
-		Page:       page,
-		Limit:      limit,
-		TotalPages: totalPages,
-	}, nil
+	if filter.Search != "" {
+		prefix := likeEscaper.Replace(strings.ToLower(filter.Search)) + "%"
+		db = db.Where("LOWER(handler) LIKE ? OR LOWER(email) LIKE ?", prefix, prefix)
+	}
+	return db
 }
 
 func (r *UserRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
@@ -114,18 +129,160 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) (*models
 		return nil, result.Error
 	}
 
-	user, err := r.FindByID(ctx, user.ID)
-	if err != nil {
-		return nil, err
+	return r.FindByID(ctx, user.ID)
+}
+
+// moderationColumns are written only by ModerationRepository, apart from
+// the verified/unverified moves SetVerificationStatus makes
+var moderationColumns = []string{"status", "suspended_until"}
+
+// UpdateColumns writes only the given columns of user. Updates go through
+// it rather than a full save so a stale copy of the user can't undo a ban
+// or suspension made since it was loaded.
+func (r *UserRepository) UpdateColumns(ctx context.Context, user *models.User, columns ...string) error {
+	for _, column := range columns {
+		if slices.Contains(moderationColumns, column) {
+			return fmt.Errorf("column %s is only written by moderation", column)
+		}
 	}
-	user.Password = ""
-	return user, nil
+	return r.db.WithContext(ctx).Model(user).Select(columns).Updates(user).Error
 }
 
-func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+// SetVerificationStatus moves the user between verified and unverified.
+// A banned user stays banned, as bans are only lifted through moderation;
+// user.Status is updated only when the change was made.
+func (r *UserRepository) SetVerificationStatus(ctx context.Context, user *models.User, status models.Status) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND status <> ?", user.ID, models.StatusBanned).
+		Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		user.Status = status
+	}
+	return nil
+}
+
+// AdvanceTOTPStep records step as the user's last accepted TOTP step if it
+// is newer than the stored one. It reports whether it was, so a code can
+// only be used once even when it is submitted twice at the same time.
+func (r *UserRepository) AdvanceTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
 }
 
 func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&models.User{}, "id = ?", id).Error
 }
+
+// FindDeactivatedByID returns a deactivated user who has not been purged
+// yet, or nil if there is none
+func (r *UserRepository) FindDeactivatedByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).
+		First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Restore reactivates a deactivated user
+func (r *UserRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).
+		Update("deleted_at", nil).Error
+}
+
+// HandlerOrEmailTaken reports whether an active user holds the handler or
+// email
+func (r *UserRepository) HandlerOrEmailTaken(ctx context.Context, handler, email string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("handler = ? OR LOWER(email) = ?", handler, models.NormalizeEmail(email)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindPurgeable returns up to limit users deactivated before the cutoff
+// and not purged yet
+func (r *UserRepository) FindPurgeable(ctx context.Context, deactivatedBefore time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at < ? AND purged_at IS NULL", deactivatedBefore).
+		Order("deleted_at").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// userDataModels are the tables holding per-user data that is deleted when
+// an account is purged
+var userDataModels = []any{
+	&models.Session{},
+	&models.RefreshToken{},
+	&models.VerificationCode{},
+	&models.PasswordResetToken{},
+	&models.RecoveryCode{},
+	&models.APIToken{},
+	&models.UserSettings{},
+}
+
+// Purge turns a deactivated user into an anonymous tombstone in one
+// transaction. Credentials, settings, follows, blocks and mutes are
+// deleted and the follow counts of the other users are corrected, while
+// the row itself stays so content the user authored still points at it.
+// The moderation audit trail is kept. Data exports are deleted too and
+// returned, so the caller can remove their archives from the blob store.
+// It reports false when the user was no longer waiting to be purged.
+func (r *UserRepository) Purge(ctx context.Context, id uuid.UUID, tombstone map[string]any) (purged bool, exports []models.DataExport, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Anonymize first so a user restored in the meantime is left alone.
+		// Through the table, as GORM never writes read-only fields.
+		tombstone["updated_at"] = time.Now()
+		result := tx.Table("users").
+			Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).
+			Updates(tombstone)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		purged = true
+
+		err := tx.Exec(`UPDATE users SET follower_count = follower_count - 1
+			WHERE id IN (SELECT followed_id FROM follows WHERE follower_id = ?)`, id).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`UPDATE users SET following_count = following_count - 1
+			WHERE id IN (SELECT follower_id FROM follows WHERE followed_id = ?)`, id).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("follower_id = ? OR followed_id = ?", id, id).Delete(&models.Follow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("blocker_id = ? OR blocked_id = ?", id, id).Delete(&models.UserBlock{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("muter_id = ? OR muted_id = ?", id, id).Delete(&models.UserMute{}).Error; err != nil {
+			return err
+		}
+		for _, model := range userDataModels {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Clauses(clause.Returning{}).Where("user_id = ?", id).Delete(&exports).Error
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return purged, exports, nil
+}
@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/sqltest"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/google/uuid"
+)
+
+// statementLog records every statement sent to a sqltest handler
+type statementLog struct {
+	statements []string
+	args       [][]driver.Value
+}
+
+func (l *statementLog) handle(query string, args []driver.Value) (*sqltest.Result, error) {
+	l.statements = append(l.statements, query)
+	l.args = append(l.args, args)
+	return &sqltest.Result{RowsAffected: 1}, nil
+}
+
+// find returns the arguments of the first statement starting with prefix
+func (l *statementLog) find(prefix string) ([]driver.Value, bool) {
+	for i, statement := range l.statements {
+		if strings.HasPrefix(statement, prefix) {
+			return l.args[i], true
+		}
+	}
+	return nil, false
+}
+
+func TestPurgeDeletesRelationshipsInBothDirections(t *testing.T) {
+	log := &statementLog{}
+	repo := NewUserRepository(sqltest.Open(t, log.handle), pagination.NewSigner([]byte("key")))
+	id := uuid.New()
+
+	purged, _, err := repo.Purge(context.Background(), id, map[string]any{"handler": "deleted"})
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if !purged {
+		t.Fatal("Purge reported the user as not purgeable")
+	}
+
+	tests := []struct {
+		table  string
+		prefix string
+	}{
+		{"follows", `DELETE FROM "follows" WHERE follower_id = $1 OR followed_id = $2`},
+		{"user_blocks", `DELETE FROM "user_blocks" WHERE blocker_id = $1 OR blocked_id = $2`},
+		{"user_mutes", `DELETE FROM "user_mutes" WHERE muter_id = $1 OR muted_id = $2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			args, ok := log.find(tt.prefix)
+			if !ok {
+				t.Fatalf("no delete from %s in:\n%s", tt.table, strings.Join(log.statements, "\n"))
+			}
+			if len(args) != 2 || args[0] != id.String() || args[1] != id.String() {
+				t.Errorf("args = %v, want the purged user twice", args)
+			}
+		})
+	}
+}
+
+func TestPurgeSkipsUserNoLongerWaiting(t *testing.T) {
+	log := &statementLog{}
+	db := sqltest.Open(t, func(query string, args []driver.Value) (*sqltest.Result, error) {
+		if strings.HasPrefix(query, `UPDATE "users"`) {
+			// Restored, or purged by another pass, since it was listed
+			log.statements = append(log.statements, query)
+			return &sqltest.Result{}, nil
+		}
+		return log.handle(query, args)
+	})
+	repo := NewUserRepository(db, pagination.NewSigner([]byte("key")))
+
+	purged, _, err := repo.Purge(context.Background(), uuid.New(), map[string]any{"handler": "deleted"})
+	if err != nil || purged {
+		t.Fatalf("Purge = %t, %v, want false, nil", purged, err)
+	}
+	if len(log.statements) != 1 {
+		t.Errorf("ran %d statements after the tombstone update found nothing:\n%s", len(log.statements)-1, strings.Join(log.statements, "\n"))
+	}
+}
+
+func TestPurgeDeletesAndReturnsDataExports(t *testing.T) {
+	log := &statementLog{}
+	id := uuid.New()
+	db := sqltest.Open(t, func(query string, args []driver.Value) (*sqltest.Result, error) {
+		if strings.HasPrefix(query, `DELETE FROM "data_exports"`) {
+			log.statements = append(log.statements, query)
+			log.args = append(log.args, args)
+			return &sqltest.Result{
+				Columns: []string{"id", "user_id", "status", "blob_key"},
+				Rows: [][]driver.Value{
+					{uuid.New().String(), id.String(), "ready", "exports/a.zip"},
+					{uuid.New().String(), id.String(), "pending", ""},
+				},
+			}, nil
+		}
+		return log.handle(query, args)
+	})
+	repo := NewUserRepository(db, pagination.NewSigner([]byte("key")))
+
+	purged, exports, err := repo.Purge(context.Background(), id, map[string]any{"handler": "deleted"})
+	if err != nil || !purged {
+		t.Fatalf("Purge = %t, %v, want true, nil", purged, err)
+	}
+	args, ok := log.find(`DELETE FROM "data_exports" WHERE user_id = $1 RETURNING *`)
+	if !ok || len(args) != 1 || args[0] != id.String() {
+		t.Fatalf("no delete of the user's data exports in:\n%s", strings.Join(log.statements, "\n"))
+	}
+	if len(exports) != 2 || exports[0].BlobKey != "exports/a.zip" {
+		t.Errorf("exports = %+v, want both deleted rows", exports)
+	}
+}
+
+func TestUpdateColumnsWritesOnlyNamedColumns(t *testing.T) {
+	log := &statementLog{}
+	repo := NewUserRepository(sqltest.Open(t, log.handle), pagination.NewSigner([]byte("key")))
+	user := &models.User{ID: uuid.New(), Name: "Jane", Status: models.StatusVerified, Role: models.RoleAdmin}
+
+	if err := repo.UpdateColumns(context.Background(), user, "name", "description"); err != nil {
+		t.Fatalf("UpdateColumns: %v", err)
+	}
+	want := `UPDATE "users" SET "name"=$1,"description"=$2,"updated_at"=$3 WHERE`
+	if len(log.statements) != 1 || !strings.HasPrefix(log.statements[0], want) {
+		t.Errorf("statements = %q, want one starting %q", log.statements, want)
+	}
+
+	// A stale copy must not write back over moderation
+	for _, column := range []string{"status", "suspended_until"} {
+		if err := repo.UpdateColumns(context.Background(), user, "name", column); err == nil {
+			t.Errorf("UpdateColumns wrote %s", column)
+		}
+	}
+	if len(log.statements) != 1 {
+		t.Errorf("ran %d statements for refused updates", len(log.statements)-1)
+	}
+}
+
+func TestSetVerificationStatusKeepsBans(t *testing.T) {
+	tests := []struct {
+		name       string
+		banned     bool
+		wantStatus models.Status
+	}{
+		{"unverified", false, models.StatusVerified},
+		{"banned", true, models.StatusBanned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var statement string
+			db := sqltest.Open(t, func(query string, args []driver.Value) (*sqltest.Result, error) {
+				statement = query
+				if tt.banned {
+					return &sqltest.Result{}, nil
+				}
+				return &sqltest.Result{RowsAffected: 1}, nil
+			})
+			repo := NewUserRepository(db, pagination.NewSigner([]byte("key")))
+			// The copy is stale: it was loaded before any ban
+			user := &models.User{ID: uuid.New(), Status: models.StatusUnverified}
+			if tt.banned {
+				user.Status = models.StatusBanned
+			}
+
+			if err := repo.SetVerificationStatus(context.Background(), user, models.StatusVerified); err != nil {
+				t.Fatalf("SetVerificationStatus: %v", err)
+			}
+			if !strings.Contains(statement, "status <> $") {
+				t.Errorf("statement %q does not leave banned users alone", statement)
+			}
+			if user.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", user.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestFindByEmailIgnoresCase(t *testing.T) {
+	log := &statementLog{}
+	repo := NewUserRepository(sqltest.Open(t, log.handle), pagination.NewSigner([]byte("key")))
+
+	if _, err := repo.FindByEmail(context.Background(), " Jane.Doe@Example.COM"); err != nil {
+		t.Fatalf("FindByEmail: %v", err)
+	}
+	args, ok := log.find(`SELECT * FROM "users" WHERE LOWER(email) = $1`)
+	if !ok || args[0] != "jane.doe@example.com" {
+		t.Errorf("statements = %q, args = %v, want a lookup of the lowercased email", log.statements, log.args)
+	}
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserSettingsRepository struct {
+	db *gorm.DB
+}
+
+func NewUserSettingsRepository(db *gorm.DB) *UserSettingsRepository {
+	return &UserSettingsRepository{
+		db: db,
+	}
+}
+
+// FindByUserID returns the user's stored settings, or nil if they have none
+func (r *UserSettingsRepository) FindByUserID(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	err := r.db.WithContext(ctx).First(&settings, "user_id = ?", userID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Update passes the user's stored document to fn while holding a row lock
+// and stores the document fn returns, so concurrent patches don't overwrite
+// each other. Users without a row start from an empty document.
+func (r *UserSettingsRepository) Update(ctx context.Context, userID uuid.UUID, fn func(current json.RawMessage) (json.RawMessage, error)) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.UserSettings{UserID: userID, Settings: json.RawMessage("{}")}).Error
+		if err != nil {
+			return err
+		}
+
+		var settings models.UserSettings
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&settings, "user_id = ?", userID).Error
+		if err != nil {
+			return err
+		}
+
+		next, err := fn(settings.Settings)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.UserSettings{}).Where("user_id = ?", userID).
+			Updates(map[string]any{"settings": next, "updated_at": time.Now()}).Error
+	})
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VerificationCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewVerificationCodeRepository(db *gorm.DB) *VerificationCodeRepository {
+	return &VerificationCodeRepository{
+		db: db,
+	}
+}
+
+// FindByUserID returns the user's pending code, or nil if none exists
+func (r *VerificationCodeRepository) FindByUserID(ctx context.Context, userID uuid.UUID) (*models.VerificationCode, error) {
+	var code models.VerificationCode
+	result := r.db.WithContext(ctx).First(&code, "user_id = ?", userID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &code, nil
+}
+
+// Upsert stores the user's code, replacing any previous one
+func (r *VerificationCodeRepository) Upsert(ctx context.Context, code *models.VerificationCode) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"code_hash", "expires_at", "attempts", "sent_at", "send_count", "window_started_at", "updated_at"}),
+	}).Create(code).Error
+}
+
+// ReserveAttempt counts an attempt against the code before it is checked,
+// unless maxAttempts was already reached. It reports whether the attempt
+// was reserved. The check and increment are one statement, so concurrent
+// guesses can't get past the limit.
+func (r *VerificationCodeRepository) ReserveAttempt(ctx context.Context, id uuid.UUID, maxAttempts int) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.VerificationCode{}).
+		Where("id = ? AND attempts < ?", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *VerificationCodeRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.VerificationCode{}, "id = ?", id).Error
+}
@@ -1,38 +1,161 @@
 package routes
 
 import (
+	"fmt"
+
 	"github.com/dfanso/reddit-clone/internal/controllers"
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/policy"
+	"github.com/dfanso/reddit-clone/internal/services"
+	"github.com/dfanso/reddit-clone/pkg/middleware"
 	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
 )
 
+// Controllers groups every controller the routes are mapped to
+type Controllers struct {
+	User     *controllers.UserController
+	Auth     *controllers.AuthController
+	OAuth    *controllers.OAuthController
+	MFA      *controllers.MFAController
+	Session  *controllers.SessionController
+	Admin    *controllers.AdminController
+	Keys     *controllers.KeysController
+	Token    *controllers.APITokenController
+	Image    *controllers.ProfileImageController
+	Settings *controllers.SettingsController
+	Follow   *controllers.FollowController
+	Block    *controllers.BlockController
+	Mute     *controllers.MuteController
+	Account  *controllers.AccountController
+	Export   *controllers.ExportController
+}
+
 // RegisterRoutes registers all application routes
-func RegisterRoutes(e *echo.Echo, userController *controllers.UserController, authController *controllers.AuthController) {
+func RegisterRoutes(e *echo.Echo, c Controllers, authenticator *middleware.Authenticator) {
+	// Key discovery lives at the well-known path, outside the API group
+	e.GET("/.well-known/jwks.json", c.Keys.JWKS)
+
 	// API group
 	api := e.Group("/api/v1")
 
 	// Register all routes
-	registerUserRoutes(api, userController)
-	registerAuthRoutes(api, authController)
+	registerUserRoutes(api, c.User, authenticator)
+	registerProfileRoutes(api, c, authenticator)
+	registerAuthRoutes(api, c.Auth, authenticator)
+	registerOAuthRoutes(api, c.OAuth)
+	registerMFARoutes(api, c.MFA, authenticator)
+	registerMeRoutes(api, c, authenticator)
+	registerAdminRoutes(api, c.Admin, authenticator)
 }
 
-// registerAuthRoutes to map /api/auth/register and /api/auth/login
-func registerAuthRoutes(api *echo.Group, authController *controllers.AuthController) {
+// registerAuthRoutes registers the public auth routes and the logout and
+// signup completion routes, which require a valid access token
+func registerAuthRoutes(api *echo.Group, authController *controllers.AuthController, authenticator *middleware.Authenticator) {
+	authMiddleware := authenticator.Middleware()
 	auth := api.Group("/auth")
 	auth.POST("/register", authController.Register)
 	auth.POST("/login", authController.Login)
+	auth.POST("/login/mfa", authController.LoginMFA)
+	auth.POST("/verify-email", authController.VerifyEmail)
+	auth.POST("/resend-verification", authController.ResendVerification)
+	auth.POST("/forgot-password", authController.ForgotPassword)
+	auth.POST("/reset-password", authController.ResetPassword)
+	auth.POST("/refresh", authController.Refresh)
+	auth.POST("/logout", authController.Logout, authMiddleware)
+	auth.POST("/logout-all", authController.LogoutAll, authMiddleware)
+	auth.PATCH("/complete-signup", authController.CompleteSignup, authenticator.SignupMiddleware())
+}
+
+// registerOAuthRoutes registers the Google sign-in redirect and callback
+func registerOAuthRoutes(api *echo.Group, oauthController *controllers.OAuthController) {
+	google := api.Group("/auth/google")
+	google.GET("/login", oauthController.GoogleLogin)
+	google.GET("/callback", oauthController.GoogleCallback)
+}
+
+// registerMFARoutes registers the two-factor management routes
+func registerMFARoutes(api *echo.Group, mfaController *controllers.MFAController, authenticator *middleware.Authenticator) {
+	mfa := api.Group("/auth/2fa", authenticator.Middleware())
+	mfa.POST("/enroll", mfaController.Enroll)
+	mfa.POST("/confirm", mfaController.Confirm)
+	mfa.POST("/disable", mfaController.Disable)
+	mfa.POST("/recovery-codes", mfaController.RegenerateRecoveryCodes)
 }
 
-// registerUserRoutes registers user-related routes
-func registerUserRoutes(api *echo.Group, userController *controllers.UserController) {
+// registerMeRoutes registers routes acting on the authenticated user.
+// Reading the profile also accepts API tokens with the read scope; the rest
+// edit the account or manage credentials, so they need a signed-in session.
+// Export downloads are authorized by their signed link alone.
+func registerMeRoutes(api *echo.Group, c Controllers, authenticator *middleware.Authenticator) {
+	authMiddleware := authenticator.Middleware()
+	// Multipart overhead on top of the largest accepted image
+	uploadLimit := echoMiddleware.BodyLimit(fmt.Sprintf("%dK", services.MaxProfileImageBytes/1024+64))
+	me := api.Group("/me")
+	me.GET("", c.Auth.Profile, authenticator.Middleware(models.ScopeRead))
+	me.PATCH("", c.Auth.UpdateProfile, authMiddleware)
+	me.DELETE("", c.Account.Deactivate, authMiddleware)
+	me.PUT("/password", c.Auth.ChangePassword, authMiddleware)
+	me.PUT("/avatar", c.Image.UploadAvatar, uploadLimit, authMiddleware)
+	me.PUT("/banner", c.Image.UploadBanner, uploadLimit, authMiddleware)
+	me.GET("/settings", c.Settings.Get, authMiddleware)
+	me.PATCH("/settings", c.Settings.Patch, authMiddleware)
+	me.GET("/blocks", c.Block.List, authMiddleware)
+	me.POST("/blocks", c.Block.Create, authMiddleware)
+	me.DELETE("/blocks/:handle", c.Block.Delete, authMiddleware)
+	me.GET("/mutes", c.Mute.List, authMiddleware)
+	me.POST("/mutes", c.Mute.Create, authMiddleware)
+	me.DELETE("/mutes/:handle", c.Mute.Delete, authMiddleware)
+	me.GET("/sessions", c.Session.List, authMiddleware)
+	me.DELETE("/sessions/:id", c.Session.Delete, authMiddleware)
+	me.GET("/tokens", c.Token.List, authMiddleware)
+	me.POST("/tokens", c.Token.Create, authMiddleware)
+	me.DELETE("/tokens/:id", c.Token.Delete, authMiddleware)
+	me.GET("/exports", c.Export.List, authMiddleware)
+	me.POST("/exports", c.Export.Create, authMiddleware)
+	me.GET("/exports/:id/download", c.Export.Download)
+}
+
+// registerAdminRoutes registers admin-only user management and moderation
+// routes
+func registerAdminRoutes(api *echo.Group, adminController *controllers.AdminController, authenticator *middleware.Authenticator) {
+	admin := api.Group("/admin", authenticator.Middleware(), policy.RequireRole(models.RoleAdmin))
+	admin.POST("/users/:id/unlock", adminController.Unlock)
+	admin.POST("/users/:id/restore", adminController.Restore)
+	admin.POST("/users/:id/suspend", adminController.Suspend)
+	admin.POST("/users/:id/ban", adminController.Ban)
+	admin.POST("/users/:id/unban", adminController.Unban)
+	admin.GET("/users/:id/moderation", adminController.ModerationHistory)
+}
+
+// registerProfileRoutes registers the public profile routes. Profiles and
+// follow lists need no authentication; following needs a signed-in user.
+func registerProfileRoutes(api *echo.Group, c Controllers, authenticator *middleware.Authenticator) {
+	authMiddleware := authenticator.Middleware()
+	profile := api.Group("/u/:handle")
+	profile.GET("", c.User.GetByHandle)
+	profile.GET("/followers", c.Follow.Followers)
+	profile.GET("/following", c.Follow.Following)
+	profile.PUT("/follow", c.Follow.Follow, authMiddleware)
+	profile.DELETE("/follow", c.Follow.Unfollow, authMiddleware)
+}
+
+// registerUserRoutes registers user-related routes, all of which require
+// a valid access token. Listing and creating users is admin-only, while
+// updates and deletes are limited to the user themselves or an admin.
+// Reading a single user also accepts API tokens with the read scope.
+func registerUserRoutes(api *echo.Group, userController *controllers.UserController, authenticator *middleware.Authenticator) {
 	users := api.Group("/users")
+	authMiddleware := authenticator.Middleware()
+	readMiddleware := authenticator.Middleware(models.ScopeRead)
+	adminOnly := policy.RequireRole(models.RoleAdmin)
+	selfOrAdmin := policy.RequireSelfOrAdmin("id")
 	{
-		users.GET("", userController.GetAll)
-		users.GET("/paginated", userController.GetPaginated)
-		users.GET("/:id", userController.GetByID)
-		users.POST("", userController.Create)
-		users.PUT("/:id", userController.Update)
-		users.DELETE("/:id", userController.Delete)
+		users.GET("", userController.GetAll, authMiddleware, adminOnly)
+		users.GET("/paginated", userController.GetPaginated, authMiddleware, adminOnly)
+		users.GET("/:id", userController.GetByID, readMiddleware)
+		users.POST("", userController.Create, authMiddleware, adminOnly)
+		users.PUT("/:id", userController.Update, authMiddleware, selfOrAdmin)
+		users.DELETE("/:id", userController.Delete, authMiddleware, selfOrAdmin)
 	}
 }
-
-//TODO: Auth middleware with protected routes
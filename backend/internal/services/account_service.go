@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/pkg/storage"
+	"github.com/google/uuid"
+)
+
+// purgeBatchSize bounds how many accounts one purge pass loads at a time
+const purgeBatchSize = 100
+
+var ErrRestoreConflict = errors.New("the handler or email now belongs to another account")
+
+// AccountService runs the account lifecycle: deactivation soft-deletes the
+// user, admins can restore them during the grace period, and afterwards the
+// purger anonymizes what is left
+type AccountService struct {
+	repo           *repositories.UserRepository
+	authService    *AuthService
+	sessionService *SessionService
+	store          storage.BlobStore
+	gracePeriod    time.Duration
+}
+
+func NewAccountService(repo *repositories.UserRepository, authService *AuthService, sessionService *SessionService, store storage.BlobStore, gracePeriod time.Duration) *AccountService {
+	return &AccountService{
+		repo:           repo,
+		authService:    authService,
+		sessionService: sessionService,
+		store:          store,
+		gracePeriod:    gracePeriod,
+	}
+}
+
+// DeactivateSelf deactivates the caller's own account and returns when it
+// will be purged. The caller confirms it is them as ConfirmIdentity
+// describes.
+func (s *AccountService) DeactivateSelf(ctx context.Context, user *models.User, sessionID uuid.UUID, password string) (time.Time, error) {
+	if err := s.sessionService.ConfirmIdentity(ctx, user, sessionID, password); err != nil {
+		return time.Time{}, err
+	}
+	return s.Deactivate(ctx, user.ID)
+}
+
+// Deactivate soft-deletes the user and signs them out everywhere, freeing
+// their handler and email. It returns when the account will be purged.
+func (s *AccountService) Deactivate(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	if err := s.repo.Delete(ctx, userID); err != nil {
+		return time.Time{}, err
+	}
+	if err := s.authService.LogoutAll(ctx, userID); err != nil {
+		return time.Time{}, fmt.Errorf("account deactivated but sessions could not be ended: %v", err)
+	}
+	return time.Now().Add(s.gracePeriod), nil
+}
+
+// Restore reactivates a deactivated account that has not been purged.
+// It fails if someone registered the handler or email in the meantime.
+func (s *AccountService) Restore(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.repo.FindDeactivatedByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	taken, err := s.repo.HandlerOrEmailTaken(ctx, user.Handler, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrRestoreConflict
+	}
+
+	if err := s.repo.Restore(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(ctx, user.ID)
+}
+
+// PurgeExpired purges every account deactivated longer than the grace
+// period ago and returns how many were purged
+func (s *AccountService) PurgeExpired(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-s.gracePeriod)
+	purged := 0
+	for {
+		users, err := s.repo.FindPurgeable(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+		for i := range users {
+			if err := s.purge(ctx, &users[i]); err != nil {
+				return purged, fmt.Errorf("failed to purge user %s: %v", users[i].ID, err)
+			}
+			purged++
+		}
+		if len(users) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// purge anonymizes the row, deletes the user's personal data and then
+// their images and export archives
+func (s *AccountService) purge(ctx context.Context, user *models.User) error {
+	// The handler stays unique among tombstones and still matches the
+	// handler format; the .invalid domain can never receive mail
+	id := strings.ReplaceAll(user.ID.String(), "-", "")
+	purged, exports, err := s.repo.Purge(ctx, user.ID, map[string]any{
+		"handler":         "deleted_" + id[:12],
+		"name":            "[deleted]",
+		"email":           "deleted+" + id + "@invalid",
+		"password":        "",
+		"avatar":          "",
+		"banner":          "",
+		"description":     "",
+		"google_id":       nil,
+		"totp_secret":     "",
+		"totp_enabled":    false,
+		"totp_last_step":  0,
+		"follower_count":  0,
+		"following_count": 0,
+		"suspended_until": nil,
+		"purged_at":       time.Now(),
+	})
+	if err != nil || !purged {
+		return err
+	}
+
+	// Leftovers here only leave orphans behind, so failures are logged
+	var keys []string
+	for _, url := range []string{user.Avatar, user.Banner} {
+		if key, ok := s.store.KeyFromURL(url); ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, export := range exports {
+		if export.BlobKey != "" {
+			keys = append(keys, export.BlobKey)
+		}
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete blob %s: %v", key, err)
+		}
+	}
+	if err := s.authService.UnlockAccount(ctx, user); err != nil {
+		log.Printf("Failed to clear login attempts of user %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+// StartPurger purges expired deactivated accounts every interval until ctx
+// is cancelled
+func (s *AccountService) StartPurger(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purged, err := s.PurgeExpired(ctx)
+				if err != nil {
+					log.Printf("Failed to purge deactivated accounts: %v", err)
+				}
+				if purged > 0 {
+					log.Printf("Purged %d deactivated accounts", purged)
+				}
+			}
+		}
+	}()
+}
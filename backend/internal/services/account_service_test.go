@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/internal/sqltest"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/dfanso/reddit-clone/pkg/storage"
+	"github.com/google/uuid"
+)
+
+func TestPurgeDeletesImagesAndExportArchives(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := "avatars/someone-else.png"
+	keys := []string{"avatars/jane.png", "banners/jane.png", "exports/ready.zip", "exports/downloaded.zip", kept}
+	for _, key := range keys {
+		if err := store.Put(ctx, key, []byte("data"), "application/octet-stream"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user := models.User{
+		ID:     uuid.New(),
+		Email:  "jane@example.com",
+		Avatar: store.URL("avatars/jane.png"),
+		Banner: store.URL("banners/jane.png"),
+	}
+	db := sqltest.Open(t, func(query string, args []driver.Value) (*sqltest.Result, error) {
+		if strings.HasPrefix(query, `DELETE FROM "data_exports"`) {
+			return &sqltest.Result{
+				Columns: []string{"id", "user_id", "status", "blob_key"},
+				Rows: [][]driver.Value{
+					{uuid.New().String(), user.ID.String(), string(models.ExportReady), "exports/ready.zip"},
+					{uuid.New().String(), user.ID.String(), string(models.ExportDownloaded), "exports/downloaded.zip"},
+					{uuid.New().String(), user.ID.String(), string(models.ExportPending), ""},
+				},
+			}, nil
+		}
+		return &sqltest.Result{RowsAffected: 1}, nil
+	})
+	authService := NewAuthService(nil, nil, nil, nil, nil, NewLoginGuard(repositories.NewMemoryLoginAttemptStore()))
+	service := NewAccountService(repositories.NewUserRepository(db, pagination.NewSigner([]byte("key"))), authService, nil, store, time.Hour)
+
+	if err := service.purge(ctx, &user); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	for _, key := range keys {
+		_, err := store.Get(ctx, key)
+		if key == kept {
+			if err != nil {
+				t.Errorf("%s: %v, want it kept", key, err)
+			}
+			continue
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: err = %v, want it deleted", key, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/google/uuid"
+)
+
+const (
+	// apiTokenBytes is the entropy of a personal API token
+	apiTokenBytes = 32
+	// apiTokenPrefixLength is how much of a token stays visible in listings
+	apiTokenPrefixLength = len(models.APITokenPrefix) + 8
+	// maxAPITokensPerUser caps the active tokens a user can hold
+	maxAPITokensPerUser = 25
+	// apiTokenTouchInterval throttles last-used writes
+	apiTokenTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIToken  = errors.New("invalid or expired API token")
+	ErrAPITokenNotFound = errors.New("API token not found")
+	ErrTooManyAPITokens = errors.New("too many active API tokens")
+)
+
+// APITokenService issues, lists, revokes and checks personal API tokens
+type APITokenService struct {
+	repo *repositories.APITokenRepository
+}
+
+func NewAPITokenService(repo *repositories.APITokenRepository) *APITokenService {
+	return &APITokenService{
+		repo: repo,
+	}
+}
+
+// Create issues a new token for the user. The plaintext token is returned
+// once and cannot be recovered afterwards.
+func (s *APITokenService) Create(ctx context.Context, user *models.User, req dto.CreateAPITokenRequest) (string, *models.APIToken, error) {
+	count, err := s.repo.CountActive(ctx, user.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	if count >= maxAPITokensPerUser {
+		return "", nil, ErrTooManyAPITokens
+	}
+
+	secret, err := auth.GenerateOpaqueToken(apiTokenBytes)
+	if err != nil {
+		return "", nil, err
+	}
+	plaintext := models.APITokenPrefix + secret
+
+	token := &models.APIToken{
+		UserID:    user.ID,
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    plaintext[:apiTokenPrefixLength],
+		TokenHash: auth.HashToken(plaintext),
+		Scopes:    req.ScopeList(),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := s.repo.Create(ctx, token); err != nil {
+		return "", nil, err
+	}
+	return plaintext, token, nil
+}
+
+func (s *APITokenService) List(ctx context.Context, userID uuid.UUID) ([]models.APIToken, error) {
+	return s.repo.FindByUserID(ctx, userID)
+}
+
+// Revoke revokes one of the user's tokens
+func (s *APITokenService) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	revoked, err := s.repo.Revoke(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// RevokeAll revokes every token of the user
+func (s *APITokenService) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	return s.repo.RevokeAll(ctx, userID)
+}
+
+// Authenticate resolves a plaintext token to its active record and records
+// the use
+func (s *APITokenService) Authenticate(ctx context.Context, plaintext string) (*models.APIToken, error) {
+	token, err := s.repo.FindByHash(ctx, auth.HashToken(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || !token.IsActive() {
+		return nil, ErrInvalidAPIToken
+	}
+
+	if err := s.repo.TouchLastUsed(ctx, token.ID, apiTokenTouchInterval); err != nil {
+		log.Printf("Failed to record API token use: %v", err)
+	}
+	return token, nil
+}
@@ -3,36 +3,183 @@ package services
 import (
 	"context"
 	"errors"
+	"log"
 
 	dto "github.com/dfanso/reddit-clone/internal/dtos"
-	models "github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrInvalidCredentials is returned for both unknown emails and wrong
+// passwords so logins can't be used to find registered emails
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// dummyPasswordHash is a bcrypt hash compared against when no user matches
+var dummyPasswordHash = func() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return string(hash)
+}()
+
 type AuthService struct {
-	userService *UserService
+	userService       *UserService
+	tokenService      *TokenService
+	revocationService *RevocationService
+	mfaService        *MFAService
+	sessionService    *SessionService
+	loginGuard        *LoginGuard
 }
 
-func NewAuthService(userService *UserService) *AuthService {
+func NewAuthService(userService *UserService, tokenService *TokenService, revocationService *RevocationService, mfaService *MFAService, sessionService *SessionService, loginGuard *LoginGuard) *AuthService {
 	return &AuthService{
-		userService: userService,
+		userService:       userService,
+		tokenService:      tokenService,
+		revocationService: revocationService,
+		mfaService:        mfaService,
+		sessionService:    sessionService,
+		loginGuard:        loginGuard,
 	}
 }
 
-func (s *AuthService) Login(ctx context.Context, req dto.LoginRequest) (*models.User, error) {
+func (s *AuthService) Login(ctx context.Context, req dto.LoginRequest, client dto.ClientInfo) (*dto.LoginResponse, error) {
+	// Refuse early while the account or IP is locked out
+	if err := s.loginGuard.Check(ctx, req.Email, client.IP); err != nil {
+		return nil, err
+	}
+
 	// Find user by email using UserService
-	user, err := s.userService.FindOne(ctx, map[string]any{"email": req.Email})
+	user, err := s.userService.FindByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, err // Return error if database fails
 	}
-	if user == nil {
-		return nil, errors.New("user not found") // No user with this email
+
+	// Check the password. Unknown emails are compared against a dummy hash
+	// so both cases take as long and return the same error.
+	passwordHash := dummyPasswordHash
+	if user != nil {
+		passwordHash = user.Password
+	}
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)) != nil || user == nil {
+		if err := s.loginGuard.RecordFailure(ctx, req.Email, client.IP); err != nil {
+			log.Printf("Failed to record login failure: %v", err)
+		}
+		return nil, ErrInvalidCredentials
 	}
 
-	// Check if the provided password matches the stored hashed password
-	if err := user.ComparePassword(req.Password); err != nil {
-		return nil, errors.New("invalid password") // Password doesn't match
+	// Only tell banned or suspended users once they proved the password
+	if err := checkAccountAccess(user); err != nil {
+		return nil, err
 	}
 
-	// Login successful, return the user
-	return user, nil
+	// With 2FA on, the password only earns a token for the second step.
+	// The account's failures are only cleared once that step passes too.
+	if user.TOTPEnabled {
+		mfaToken, err := s.mfaService.IssuePendingToken(user)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.LoginResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		}, nil
+	}
+
+	// Start a new session and issue its tokens
+	tokens, err := s.tokenService.IssueTokens(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loginGuard.RecordSuccess(ctx, user.Email); err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+
+	// Login successful, return the tokens with a sanitized user
+	return &dto.LoginResponse{
+		TokenResponse: tokens,
+		User:          dto.NewPrivateUserResponse(user),
+	}, nil
+}
+
+// LoginMFA completes a login with a 2FA code. Failed codes count against
+// the account the pending token belongs to as well as the client IP, so
+// rotating IPs or fetching new pending tokens doesn't buy more guesses.
+func (s *AuthService) LoginMFA(ctx context.Context, req dto.MFALoginRequest, client dto.ClientInfo) (*dto.LoginResponse, error) {
+	email := ""
+	if user, err := s.mfaService.PendingUser(ctx, req.MFAToken); err == nil {
+		email = user.Email
+	}
+	if err := s.loginGuard.Check(ctx, email, client.IP); err != nil {
+		return nil, err
+	}
+
+	result, err := s.mfaService.CompleteLogin(ctx, req, client)
+	if errors.Is(err, ErrInvalidMFACode) || errors.Is(err, ErrInvalidMFAToken) {
+		if err := s.loginGuard.RecordFailure(ctx, email, client.IP); err != nil {
+			log.Printf("Failed to record login failure: %v", err)
+		}
+	}
+	if err == nil {
+		if err := s.loginGuard.RecordSuccess(ctx, email); err != nil {
+			log.Printf("Failed to reset login failures: %v", err)
+		}
+	}
+	return result, err
+}
+
+// UnlockAccount clears the failed login count and lockout of a user
+func (s *AuthService) UnlockAccount(ctx context.Context, user *models.User) error {
+	return s.loginGuard.Unlock(ctx, user.Email)
+}
+
+// Refresh rotates a refresh token and returns a new token pair
+func (s *AuthService) Refresh(ctx context.Context, req dto.RefreshRequest) (*dto.TokenResponse, error) {
+	return s.tokenService.Refresh(ctx, req.RefreshToken)
+}
+
+// Logout ends the session the access token belongs to: the token itself is
+// revoked and the session is deleted along with its refresh tokens
+func (s *AuthService) Logout(ctx context.Context, claims *auth.JWTClaims) error {
+	if err := s.revocationService.RevokeToken(ctx, claims); err != nil {
+		return errors.New("failed to revoke access token")
+	}
+	if err := s.sessionService.Revoke(ctx, claims.UserID, claims.SessionID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return errors.New("failed to end session")
+	}
+	return nil
+}
+
+// ChangePassword replaces the user's password after checking the current
+// one, then signs out every other session
+func (s *AuthService) ChangePassword(ctx context.Context, user *models.User, sessionID uuid.UUID, req dto.ChangePasswordRequest) error {
+	if err := user.ComparePassword(req.CurrentPassword); err != nil {
+		return ErrInvalidPassword
+	}
+
+	user.Password = req.NewPassword
+	if err := user.HashPassword(); err != nil {
+		return errors.New("failed to hash password")
+	}
+	if err := s.userService.UpdateColumns(ctx, user, "password"); err != nil {
+		return errors.New("failed to update password")
+	}
+
+	if err := s.sessionService.RevokeOthers(ctx, user.ID, sessionID); err != nil {
+		return errors.New("failed to end other sessions")
+	}
+	return nil
+}
+
+// LogoutAll ends every session of the user
+func (s *AuthService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if err := s.revocationService.RevokeAllForUser(ctx, userID); err != nil {
+		return errors.New("failed to revoke access tokens")
+	}
+	if err := s.sessionService.RevokeAll(ctx, userID); err != nil {
+		return errors.New("failed to end sessions")
+	}
+	return nil
 }
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/google/uuid"
+)
+
+var ErrCannotBlockSelf = errors.New("you cannot block yourself")
+
+// BlockService manages the users a user has blocked. A block hides the
+// blocked user's content and notifications from the blocker, and stops the
+// two following, messaging or mentioning each other.
+type BlockService struct {
+	repo        *repositories.BlockRepository
+	userService *UserService
+}
+
+func NewBlockService(repo *repositories.BlockRepository, userService *UserService) *BlockService {
+	return &BlockService{
+		repo:        repo,
+		userService: userService,
+	}
+}
+
+// Block makes blocker block the user with the given handler and removes
+// any follow between them. Blocking someone twice is not an error.
+func (s *BlockService) Block(ctx context.Context, blocker *models.User, handler string) error {
+	target, err := s.userService.FindOne(ctx, map[string]any{"handler": handler})
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrUserNotFound
+	}
+	if target.ID == blocker.ID {
+		return ErrCannotBlockSelf
+	}
+	_, err = s.repo.Create(ctx, blocker.ID, target.ID)
+	return err
+}
+
+// Unblock lifts blocker's block on the user with the given handler
+func (s *BlockService) Unblock(ctx context.Context, blocker *models.User, handler string) error {
+	target, err := s.userService.FindOne(ctx, map[string]any{"handler": handler})
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrUserNotFound
+	}
+	_, err = s.repo.Delete(ctx, blocker.ID, target.ID)
+	return err
+}
+
+// List returns one page of the users blockerID blocked
+func (s *BlockService) List(ctx context.Context, blockerID uuid.UUID, params pagination.Params) (*pagination.Page[types.BlockListEntry], error) {
+	return s.repo.FindPage(ctx, blockerID, params)
+}
+
+// CanInteract reports whether actorID may direct something at targetID,
+// such as a follow, direct message or mention. It is false when either
+// user blocked the other.
+func (s *BlockService) CanInteract(ctx context.Context, actorID, targetID uuid.UUID) (bool, error) {
+	blocked, err := s.repo.ExistsBetween(ctx, actorID, targetID)
+	if err != nil {
+		return false, err
+	}
+	return !blocked, nil
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/pkg/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	// ExportKeyPrefix is where export archives live in the blob store. They
+	// must only be served through signed download links.
+	ExportKeyPrefix = "exports/"
+	// maxExportsPerWindow caps how many exports a user can request per
+	// exportRateWindow. Failed exports don't count.
+	maxExportsPerWindow = 3
+	exportRateWindow    = 7 * 24 * time.Hour
+	// exportStaleAfter is how long an export can sit in processing before
+	// another worker picks it up again
+	exportStaleAfter = 15 * time.Minute
+	// exportListLimit bounds how many past exports are listed
+	exportListLimit = 20
+	// exportBatchSize bounds how many expired exports one sweep loads
+	exportBatchSize = 100
+)
+
+// exportNotYetAvailable names the personal data the archive can't include
+// yet because the features that store it don't exist in this tree. The
+// manifest lists them so the archive doesn't pass for complete; each moves
+// into build as its feature lands.
+var exportNotYetAvailable = []string{"posts", "comments", "votes", "saved_items", "messages"}
+
+var (
+	ErrExportInProgress  = errors.New("an export is already being prepared")
+	ErrInvalidExportLink = errors.New("invalid or expired download link")
+	ErrExportUnavailable = errors.New("export was already downloaded or has expired")
+)
+
+// ExportRateLimitedError is returned when the user requested too many
+// exports recently
+type ExportRateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ExportRateLimitedError) Error() string {
+	return fmt.Sprintf("too many data exports requested, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// ExportService builds ZIP archives of a user's personal data in the
+// background and hands each one out once through a signed, expiring link
+type ExportService struct {
+	repo              *repositories.DataExportRepository
+	userService       *UserService
+	settingsService   *SettingsService
+	sessionService    *SessionService
+	apiTokenService   *APITokenService
+	moderationService *ModerationService
+	followRepo        *repositories.FollowRepository
+	blockRepo         *repositories.BlockRepository
+	muteRepo          *repositories.MuteRepository
+	store             storage.BlobStore
+	linkKey           []byte
+	ttl               time.Duration
+	wake              chan struct{}
+}
+
+func NewExportService(
+	repo *repositories.DataExportRepository,
+	userService *UserService,
+	settingsService *SettingsService,
+	sessionService *SessionService,
+	apiTokenService *APITokenService,
+	moderationService *ModerationService,
+	followRepo *repositories.FollowRepository,
+	blockRepo *repositories.BlockRepository,
+	muteRepo *repositories.MuteRepository,
+	store storage.BlobStore,
+	linkKey []byte,
+	ttl time.Duration,
+) *ExportService {
+	return &ExportService{
+		repo:              repo,
+		userService:       userService,
+		settingsService:   settingsService,
+		sessionService:    sessionService,
+		apiTokenService:   apiTokenService,
+		moderationService: moderationService,
+		followRepo:        followRepo,
+		blockRepo:         blockRepo,
+		muteRepo:          muteRepo,
+		store:             store,
+		linkKey:           linkKey,
+		ttl:               ttl,
+		wake:              make(chan struct{}, 1),
+	}
+}
+
+// Request queues a new export for the user and wakes the worker. Users get
+// one unfinished export at a time and maxExportsPerWindow per
+// exportRateWindow, both enforced by the database.
+func (s *ExportService) Request(ctx context.Context, userID uuid.UUID) (*models.DataExport, error) {
+	now := time.Now()
+	export := &models.DataExport{
+		UserID: userID,
+		Status: models.ExportPending,
+	}
+	created, counted, err := s.repo.CreateWithinLimit(ctx, export, now.Add(-exportRateWindow), maxExportsPerWindow)
+	if err != nil {
+		return nil, err
+	}
+	if counted != nil {
+		// A slot frees up once the oldest counted export leaves the window
+		oldest := counted[len(counted)-maxExportsPerWindow]
+		return nil, &ExportRateLimitedError{RetryAfter: oldest.CreatedAt.Add(exportRateWindow).Sub(now)}
+	}
+	if !created {
+		return nil, ErrExportInProgress
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return export, nil
+}
+
+// List returns the user's recent exports, newest first
+func (s *ExportService) List(ctx context.Context, userID uuid.UUID) ([]models.DataExport, error) {
+	return s.repo.FindByUserID(ctx, userID, exportListLimit)
+}
+
+// DownloadSignature signs a ready export's download link. The link is
+// valid until the export expires.
+func (s *ExportService) DownloadSignature(export *models.DataExport) (expires int64, signature string) {
+	expires = export.ExpiresAt.Unix()
+	return expires, base64.RawURLEncoding.EncodeToString(s.sign(export.ID, expires))
+}
+
+// Download checks a signed link and claims the export, returning its
+// archive. The blob is deleted when the returned reader is closed, so the
+// export can only be downloaded once.
+func (s *ExportService) Download(ctx context.Context, id uuid.UUID, expires int64, signature string) (io.ReadCloser, *models.DataExport, error) {
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.sign(id, expires)) || time.Now().Unix() >= expires {
+		return nil, nil, ErrInvalidExportLink
+	}
+
+	// Open the archive before claiming so a storage hiccup doesn't use up
+	// the only download
+	export, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export == nil || export.Status != models.ExportReady {
+		return nil, nil, ErrExportUnavailable
+	}
+	body, err := s.store.Get(ctx, export.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, ErrExportUnavailable
+		}
+		return nil, nil, err
+	}
+
+	claimed, err := s.repo.ClaimDownload(ctx, id)
+	if err != nil || claimed == nil {
+		body.Close()
+		if err == nil {
+			err = ErrExportUnavailable
+		}
+		return nil, nil, err
+	}
+	return &deleteOnClose{ReadCloser: body, store: s.store, key: claimed.BlobKey}, claimed, nil
+}
+
+func (s *ExportService) sign(id uuid.UUID, expires int64) []byte {
+	mac := hmac.New(sha256.New, s.linkKey)
+	mac.Write([]byte(id.String() + "." + strconv.FormatInt(expires, 10)))
+	return mac.Sum(nil)
+}
+
+// deleteOnClose removes a downloaded export from the blob store once it
+// has been streamed
+type deleteOnClose struct {
+	io.ReadCloser
+	store storage.BlobStore
+	key   string
+}
+
+func (d *deleteOnClose) Close() error {
+	err := d.ReadCloser.Close()
+	// The request may be gone by now, so don't tie the delete to it
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if deleteErr := d.store.Delete(ctx, d.key); deleteErr != nil {
+		log.Printf("Failed to delete downloaded export %s: %v", d.key, deleteErr)
+	}
+	return err
+}
+
+// ProcessPending builds every queued export and returns how many were
+// built
+func (s *ExportService) ProcessPending(ctx context.Context) (int, error) {
+	built := 0
+	for {
+		export, err := s.repo.ClaimNext(ctx, time.Now().Add(-exportStaleAfter))
+		if err != nil {
+			return built, err
+		}
+		if export == nil {
+			return built, nil
+		}
+		if err := s.process(ctx, export); err != nil {
+			log.Printf("Failed to build data export %s: %v", export.ID, err)
+			if err := s.repo.MarkFailed(ctx, export.ID, err.Error()); err != nil {
+				return built, err
+			}
+			continue
+		}
+		built++
+	}
+}
+
+// process builds the archive and stores it under an unguessable key
+func (s *ExportService) process(ctx context.Context, export *models.DataExport) error {
+	data, err := s.build(ctx, export)
+	if err != nil {
+		return err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	key := ExportKeyPrefix + export.UserID.String() + "/" + hex.EncodeToString(token) + ".zip"
+	if err := s.store.Put(ctx, key, data, "application/zip"); err != nil {
+		return err
+	}
+	if err := s.repo.MarkReady(ctx, export.ID, key, int64(len(data)), time.Now().Add(s.ttl)); err != nil {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete blob %s: %v", key, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// build collects the user's data and writes one JSON file per category
+func (s *ExportService) build(ctx context.Context, export *models.DataExport) ([]byte, error) {
+	user, err := s.userService.GetByID(ctx, export.UserID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.settingsService.Get(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessionService.List(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	apiTokens, err := s.apiTokenService.List(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	followers, err := s.followRepo.FindAllFollowers(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	following, err := s.followRepo.FindAllFollowing(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := s.blockRepo.FindAll(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	mutes, err := s.muteRepo.FindAll(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	moderation, err := s.moderationService.History(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionResponses := make([]*dto.SessionResponse, len(sessions))
+	for i := range sessions {
+		sessionResponses[i] = dto.NewSessionResponse(&sessions[i], uuid.Nil)
+	}
+	apiTokenResponses := make([]*dto.APITokenResponse, len(apiTokens))
+	for i := range apiTokens {
+		apiTokenResponses[i] = dto.NewAPITokenResponse(&apiTokens[i])
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", dto.NewPrivateUserResponse(user)},
+		{"settings.json", settings},
+		{"sessions.json", sessionResponses},
+		{"api_tokens.json", apiTokenResponses},
+		{"followers.json", dto.NewFollowUserResponses(followers)},
+		{"following.json", dto.NewFollowUserResponses(following)},
+		{"blocks.json", dto.NewBlockedUserResponses(blocks)},
+		{"mutes.json", dto.NewMutedUserResponses(mutes)},
+		{"moderation_history.json", dto.NewModerationActionResponses(moderation)},
+	}
+
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.name
+	}
+	manifest := map[string]any{
+		"exportId":        export.ID,
+		"userId":          user.ID,
+		"generatedAt":     time.Now().UTC(),
+		"files":           names,
+		"notYetAvailable": exportNotYetAvailable,
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	if err := writeJSONFile(archive, "manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := writeJSONFile(archive, file.name, file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSONFile(archive *zip.Writer, name string, v any) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return nil
+}
+
+// ExpireStale deletes the archives of exports that were not downloaded in
+// time and returns how many were expired
+func (s *ExportService) ExpireStale(ctx context.Context) (int, error) {
+	expired := 0
+	for {
+		exports, err := s.repo.FindExpired(ctx, time.Now(), exportBatchSize)
+		if err != nil {
+			return expired, err
+		}
+		for _, export := range exports {
+			if err := s.store.Delete(ctx, export.BlobKey); err != nil {
+				return expired, fmt.Errorf("failed to delete export %s: %v", export.ID, err)
+			}
+			if err := s.repo.MarkExpired(ctx, export.ID); err != nil {
+				return expired, err
+			}
+			expired++
+		}
+		if len(exports) < exportBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// StartWorker builds queued exports and expires old ones every interval,
+// or as soon as a new export is requested, until ctx is cancelled
+func (s *ExportService) StartWorker(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				expired, err := s.ExpireStale(ctx)
+				if err != nil {
+					log.Printf("Failed to expire data exports: %v", err)
+				}
+				if expired > 0 {
+					log.Printf("Expired %d data exports", expired)
+				}
+			case <-s.wake:
+			}
+			if _, err := s.ProcessPending(ctx); err != nil {
+				log.Printf("Failed to process data exports: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+)
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrCannotFollowSelf = errors.New("you cannot follow yourself")
+	ErrFollowNotAllowed = errors.New("you cannot follow this user")
+)
+
+// FollowService manages who follows whom
+type FollowService struct {
+	repo        *repositories.FollowRepository
+	userService *UserService
+}
+
+func NewFollowService(repo *repositories.FollowRepository, userService *UserService) *FollowService {
+	return &FollowService{
+		repo:        repo,
+		userService: userService,
+	}
+}
+
+// Follow makes follower follow the user with the given handler. Following
+// is refused when either user blocked the other or either is banned.
+// Following someone twice is not an error.
+func (s *FollowService) Follow(ctx context.Context, follower *models.User, handler string) error {
+	// Banned users and unfinished signups have no public profile
+	target, err := s.userService.GetPublicByHandler(ctx, handler)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrUserNotFound
+	}
+	if target.ID == follower.ID {
+		return ErrCannotFollowSelf
+	}
+	if follower.Status == models.StatusBanned {
+		return ErrFollowNotAllowed
+	}
+
+	// The block check runs inside the insert, so a block racing this
+	// follow can't leave both in place
+	_, blocked, err := s.repo.Create(ctx, follower.ID, target.ID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrFollowNotAllowed
+	}
+	return nil
+}
+
+// Unfollow stops follower following the user with the given handler. It
+// works even if that user has since been banned.
+func (s *FollowService) Unfollow(ctx context.Context, follower *models.User, handler string) error {
+	target, err := s.userService.FindOne(ctx, map[string]any{"handler": handler})
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrUserNotFound
+	}
+	_, err = s.repo.Delete(ctx, follower.ID, target.ID)
+	return err
+}
+
+// Followers returns one page of the users following the user with the
+// given handler
+func (s *FollowService) Followers(ctx context.Context, handler string, params pagination.Params) (*pagination.Page[types.FollowListEntry], error) {
+	user, err := s.publicUser(ctx, handler)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindFollowersPage(ctx, user.ID, params)
+}
+
+// Following returns one page of the users the user with the given handler
+// follows
+func (s *FollowService) Following(ctx context.Context, handler string, params pagination.Params) (*pagination.Page[types.FollowListEntry], error) {
+	user, err := s.publicUser(ctx, handler)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindFollowingPage(ctx, user.ID, params)
+}
+
+func (s *FollowService) publicUser(ctx context.Context, handler string) (*models.User, error) {
+	user, err := s.userService.GetPublicByHandler(ctx, handler)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/dfanso/reddit-clone/pkg/oidc"
+)
+
+const (
+	googleProvider = "google"
+	oauthStateTTL  = 10 * time.Minute
+)
+
+var (
+	ErrGoogleSSODisabled     = errors.New("Google sign-in is not configured")
+	ErrInvalidOAuthState     = errors.New("invalid or expired sign-in attempt")
+	ErrGoogleEmailUnverified = errors.New("Google account email is not verified")
+)
+
+// GoogleLoginResult is the outcome of a completed Google sign-in
+type GoogleLoginResult struct {
+	User   *models.User
+	Tokens *dto.TokenResponse
+	// NeedsDetails is set when the user still has to complete signup
+	NeedsDetails bool
+	// MFAToken is set instead of Tokens when the user has 2FA enabled and
+	// still has to pass the second step
+	MFAToken string
+}
+
+// GoogleAuthService signs users in with Google through OpenID Connect
+type GoogleAuthService struct {
+	repo            *repositories.OAuthStateRepository
+	userService     *UserService
+	tokenService    *TokenService
+	authService     *AuthService
+	mfaService      *MFAService
+	apiTokenService *APITokenService
+	provider        *oidc.Provider
+}
+
+func NewGoogleAuthService(repo *repositories.OAuthStateRepository, userService *UserService, tokenService *TokenService, authService *AuthService, mfaService *MFAService, apiTokenService *APITokenService, provider *oidc.Provider) *GoogleAuthService {
+	return &GoogleAuthService{
+		repo:            repo,
+		userService:     userService,
+		tokenService:    tokenService,
+		authService:     authService,
+		mfaService:      mfaService,
+		apiTokenService: apiTokenService,
+		provider:        provider,
+	}
+}
+
+// Begin starts a sign-in attempt and returns the provider URL to redirect
+// to along with the state value the callback must echo back
+func (s *GoogleAuthService) Begin(ctx context.Context) (string, string, error) {
+	if !s.provider.Enabled() {
+		return "", "", ErrGoogleSSODisabled
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomString(48)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Clear out abandoned attempts while we're here
+	if err := s.repo.DeleteExpired(ctx, time.Now()); err != nil {
+		log.Printf("Failed to delete expired OAuth states: %v", err)
+	}
+
+	err = s.repo.Create(ctx, &models.OAuthState{
+		StateHash:    auth.HashToken(state),
+		Provider:     googleProvider,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	})
+	if err != nil {
+		return "", "", errors.New("failed to store sign-in state")
+	}
+
+	return s.provider.AuthCodeURL(state, nonce, verifier), state, nil
+}
+
+// Callback finishes a sign-in attempt: it exchanges the code, verifies the
+// ID token, then finds, links or creates the user and issues tokens, or a
+// pending 2FA token when the user has 2FA enabled
+func (s *GoogleAuthService) Callback(ctx context.Context, state, code string, client dto.ClientInfo) (*GoogleLoginResult, error) {
+	if !s.provider.Enabled() {
+		return nil, ErrGoogleSSODisabled
+	}
+
+	pending, err := s.repo.Consume(ctx, auth.HashToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil || pending.Provider != googleProvider {
+		return nil, ErrInvalidOAuthState
+	}
+
+	rawIDToken, err := s.provider.Exchange(ctx, code, pending.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.provider.VerifyIDToken(ctx, rawIDToken, pending.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.findOrCreateUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Google only stands in for the password, so 2FA still applies
+	if user.TOTPEnabled {
+		if err := checkAccountAccess(user); err != nil {
+			return nil, err
+		}
+		mfaToken, err := s.mfaService.IssuePendingToken(user)
+		if err != nil {
+			return nil, err
+		}
+		return &GoogleLoginResult{User: user, MFAToken: mfaToken}, nil
+	}
+
+	tokens, err := s.tokenService.IssueTokens(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GoogleLoginResult{
+		User:         user,
+		Tokens:       tokens,
+		NeedsDetails: user.Stage != models.StageCompleted,
+	}, nil
+}
+
+// findOrCreateUser resolves the Google account to a user: first by Google
+// subject, then by verified email (linking the account), and otherwise by
+// creating a new user at the Google SSO stage
+func (s *GoogleAuthService) findOrCreateUser(ctx context.Context, claims *oidc.IDTokenClaims) (*models.User, error) {
+	user, err := s.userService.FindOne(ctx, map[string]any{"google_id": claims.Subject})
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		return user, nil
+	}
+
+	if !claims.EmailVerified || claims.Email == "" {
+		return nil, ErrGoogleEmailUnverified
+	}
+
+	user, err = s.userService.FindByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return s.createUser(ctx, claims)
+	}
+	if user.Stage == models.StageEmailVerification {
+		return s.reclaimUser(ctx, user, claims)
+	}
+
+	// The email was verified by this account too, so it belongs to the
+	// same person. Link the Google account.
+	user.GoogleID = &claims.Subject
+	if err := s.userService.UpdateColumns(ctx, user, "google_id"); err != nil {
+		return nil, errors.New("failed to link Google account")
+	}
+	return user, nil
+}
+
+func (s *GoogleAuthService) createUser(ctx context.Context, claims *oidc.IDTokenClaims) (*models.User, error) {
+	user := &models.User{
+		Email: claims.Email,
+		Role:  models.RoleUser,
+	}
+	if err := s.applyGoogleAccount(ctx, user, claims); err != nil {
+		return nil, err
+	}
+
+	createdUser, err := s.userService.Create(ctx, user)
+	if err != nil {
+		return nil, errors.New("failed to create user")
+	}
+	return createdUser, nil
+}
+
+// reclaimUser hands an account whose email was never verified to the
+// Google user who proved they own it. Whoever registered it may have been
+// someone else, so the account starts over as a new Google signup: its
+// password and profile are replaced and everything signed in is ended.
+func (s *GoogleAuthService) reclaimUser(ctx context.Context, user *models.User, claims *oidc.IDTokenClaims) (*models.User, error) {
+	// The status is set below, where a ban is kept
+	status := user.Status
+	if err := s.applyGoogleAccount(ctx, user, claims); err != nil {
+		return nil, err
+	}
+	user.Status = status
+	user.Banner = ""
+	user.Description = ""
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	err := s.userService.UpdateColumns(ctx, user, "handler", "name", "password", "avatar", "banner", "description",
+		"google_id", "stage", "totp_secret", "totp_enabled", "totp_last_step")
+	if err != nil {
+		return nil, errors.New("failed to link Google account")
+	}
+	if err := s.userService.SetVerificationStatus(ctx, user, models.StatusVerified); err != nil {
+		return nil, errors.New("failed to link Google account")
+	}
+
+	if err := s.authService.LogoutAll(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.apiTokenService.RevokeAll(ctx, user.ID); err != nil {
+		return nil, errors.New("failed to revoke API tokens")
+	}
+	return user, nil
+}
+
+// applyGoogleAccount sets up user as a Google signup that still has to
+// pick its handler and name
+func (s *GoogleAuthService) applyGoogleAccount(ctx context.Context, user *models.User, claims *oidc.IDTokenClaims) error {
+	handler, err := s.placeholderHandler(ctx)
+	if err != nil {
+		return err
+	}
+
+	// SSO users sign in through Google, so their password is random and
+	// never shown to anyone
+	password, err := auth.GenerateOpaqueToken(32)
+	if err != nil {
+		return errors.New("failed to generate password")
+	}
+
+	name := claims.Name
+	if len(name) < 2 || len(name) > 50 {
+		name = handler
+	}
+	avatar := claims.Picture
+	if len(avatar) > 255 {
+		avatar = ""
+	}
+
+	user.Handler = handler
+	user.Name = name
+	user.Password = password
+	user.Avatar = avatar
+	user.GoogleID = &claims.Subject
+	user.Status = models.StatusVerified
+	user.Stage = models.StageGoogleSSO
+	if err := user.HashPassword(); err != nil {
+		return errors.New("failed to hash password")
+	}
+	return nil
+}
+
+// placeholderHandler picks an unused handler the user replaces when they
+// complete signup
+func (s *GoogleAuthService) placeholderHandler(ctx context.Context) (string, error) {
+	for i := 0; i < 5; i++ {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		handler := fmt.Sprintf("user_%s", hex.EncodeToString(b))
+		existing, err := s.userService.FindOne(ctx, map[string]any{"handler": handler})
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return handler, nil
+		}
+	}
+	return "", errors.New("failed to pick a handler")
+}
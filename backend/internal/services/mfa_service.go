@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/dfanso/reddit-clone/pkg/encryption"
+	"github.com/dfanso/reddit-clone/pkg/totp"
+	"github.com/google/uuid"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	ErrMFADisabled          = errors.New("two-factor authentication is not configured on this server")
+	ErrMFAAlreadyEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled       = errors.New("two-factor authentication enrollment has not been started")
+	ErrMFANotEnabled        = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMFACode       = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken      = errors.New("invalid or expired two-factor login token")
+	ErrInvalidPassword      = errors.New("invalid password")
+	recoveryCodeEncoding    = base32.StdEncoding.WithPadding(base32.NoPadding)
+	recoveryCodeReplacement = strings.NewReplacer("-", "", " ", "")
+)
+
+// MFAService manages TOTP two-factor authentication
+type MFAService struct {
+	recoveryRepo      *repositories.RecoveryCodeRepository
+	userService       *UserService
+	tokenService      *TokenService
+	revocationService *RevocationService
+	sessionService    *SessionService
+	jwtManager        *auth.JWTManager
+	cipher            *encryption.Cipher // nil when no key is configured
+	issuer            string
+}
+
+func NewMFAService(recoveryRepo *repositories.RecoveryCodeRepository, userService *UserService, tokenService *TokenService, revocationService *RevocationService, sessionService *SessionService, jwtManager *auth.JWTManager, cipher *encryption.Cipher, issuer string) *MFAService {
+	return &MFAService{
+		recoveryRepo:      recoveryRepo,
+		userService:       userService,
+		tokenService:      tokenService,
+		revocationService: revocationService,
+		sessionService:    sessionService,
+		jwtManager:        jwtManager,
+		cipher:            cipher,
+		issuer:            issuer,
+	}
+}
+
+// Enroll creates a new TOTP secret for the user. 2FA stays off until the
+// user confirms it with a first code.
+func (s *MFAService) Enroll(ctx context.Context, user *models.User) (*dto.MFAEnrollResponse, error) {
+	if s.cipher == nil {
+		return nil, ErrMFADisabled
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errors.New("failed to generate secret")
+	}
+	encrypted, err := s.cipher.Encrypt(secret)
+	if err != nil {
+		return nil, errors.New("failed to encrypt secret")
+	}
+
+	user.TOTPSecret = encrypted
+	user.TOTPLastStep = 0
+	if err := s.userService.UpdateColumns(ctx, user, "totp_secret", "totp_last_step"); err != nil {
+		return nil, errors.New("failed to store secret")
+	}
+
+	return &dto.MFAEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.KeyURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm turns 2FA on once the user proves their app produces valid
+// codes, and returns the initial recovery codes
+func (s *MFAService) Confirm(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if s.cipher == nil {
+		return nil, ErrMFADisabled
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	if err := s.checkTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabled = true
+	if err := s.userService.UpdateColumns(ctx, user, "totp_enabled"); err != nil {
+		return nil, errors.New("failed to enable two-factor authentication")
+	}
+	return s.replaceRecoveryCodes(ctx, user)
+}
+
+// Disable turns 2FA off. It needs the password, or for Google users a
+// recent sign-in as ConfirmIdentity describes, and a current code or an
+// unused recovery code.
+func (s *MFAService) Disable(ctx context.Context, user *models.User, sessionID uuid.UUID, req dto.MFADisableRequest) error {
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if err := s.sessionService.ConfirmIdentity(ctx, user, sessionID, req.Password); err != nil {
+		return err
+	}
+	if err := s.checkSecondFactor(ctx, user, req.Code, req.RecoveryCode); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if err := s.userService.UpdateColumns(ctx, user, "totp_enabled", "totp_secret", "totp_last_step"); err != nil {
+		return errors.New("failed to disable two-factor authentication")
+	}
+	return s.recoveryRepo.DeleteAllForUser(ctx, user.ID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a
+// current code
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, user *models.User, code string) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.checkTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, user)
+}
+
+// IssuePendingToken returns the token handed out after a correct password
+// when the user still has to pass 2FA
+func (s *MFAService) IssuePendingToken(user *models.User) (string, error) {
+	token, err := s.jwtManager.GenerateMFAPendingToken(user.ID)
+	if err != nil {
+		return "", errors.New("failed to generate two-factor login token")
+	}
+	return token, nil
+}
+
+// PendingUser returns the user a pending 2FA token was issued to without
+// consuming the token
+func (s *MFAService) PendingUser(ctx context.Context, token string) (*models.User, error) {
+	claims, err := s.jwtManager.ValidateMFAPendingToken(token)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	user, err := s.userService.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	return user, nil
+}
+
+// CompleteLogin finishes a two-step login with a TOTP or recovery code.
+// The pending token is single-use.
+func (s *MFAService) CompleteLogin(ctx context.Context, req dto.MFALoginRequest, client dto.ClientInfo) (*dto.LoginResponse, error) {
+	claims, err := s.jwtManager.ValidateMFAPendingToken(req.MFAToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	revoked, err := s.revocationService.IsRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.userService.GetByID(ctx, claims.UserID)
+	if err != nil || !user.TOTPEnabled {
+		return nil, ErrInvalidMFAToken
+	}
+	if err := s.checkSecondFactor(ctx, user, req.Code, req.RecoveryCode); err != nil {
+		return nil, err
+	}
+	if err := s.revocationService.RevokeToken(ctx, claims); err != nil {
+		return nil, errors.New("failed to consume two-factor login token")
+	}
+
+	tokens, err := s.tokenService.IssueTokens(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.LoginResponse{
+		TokenResponse: tokens,
+		User:          dto.NewPrivateUserResponse(user),
+	}, nil
+}
+
+// checkSecondFactor accepts either a TOTP code or a recovery code
+func (s *MFAService) checkSecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) error {
+	if code != "" {
+		return s.checkTOTP(ctx, user, code)
+	}
+	if recoveryCode == "" {
+		return ErrInvalidMFACode
+	}
+	consumed, err := s.recoveryRepo.Consume(ctx, user.ID, hashRecoveryCode(recoveryCode))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// checkTOTP validates a code and records its time step, refusing a code
+// whose step was already used
+func (s *MFAService) checkTOTP(ctx context.Context, user *models.User, code string) error {
+	if s.cipher == nil {
+		return ErrMFADisabled
+	}
+	secret, err := s.cipher.Decrypt(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return ErrInvalidMFACode
+	}
+	// The stored step may have moved on since user was loaded, so the
+	// update only applies while it is still older
+	advanced, err := s.userService.AdvanceTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidMFACode
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+func (s *MFAService) replaceRecoveryCodes(ctx context.Context, user *models.User) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, errors.New("failed to generate recovery codes")
+		}
+		codes[i] = code
+		records[i] = models.RecoveryCode{
+			UserID:   user.ID,
+			CodeHash: hashRecoveryCode(code),
+		}
+	}
+	if err := s.recoveryRepo.Replace(ctx, user.ID, records); err != nil {
+		return nil, errors.New("failed to store recovery codes")
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns an 80-bit code formatted as xxxx-xxxx-xxxx-xxxx
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+	return raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16], nil
+}
+
+// hashRecoveryCode hashes a code ignoring case, dashes and spaces
+func hashRecoveryCode(code string) string {
+	return auth.HashToken(strings.ToLower(recoveryCodeReplacement.Replace(code)))
+}
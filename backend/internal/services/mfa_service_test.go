@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/internal/sqltest"
+	"github.com/dfanso/reddit-clone/pkg/encryption"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/dfanso/reddit-clone/pkg/totp"
+	"github.com/google/uuid"
+)
+
+// totpUser is a user with 2FA enabled, with the stored step kept in
+// memory by the sqltest handler
+type totpUser struct {
+	user             models.User
+	secret           string
+	lastStep         int64
+	sessionCreatedAt time.Time // Zero when the session is gone
+	disabled         bool
+}
+
+func (u *totpUser) handle(query string, args []driver.Value) (*sqltest.Result, error) {
+	if strings.HasPrefix(query, `UPDATE "users" SET "totp_last_step"=`) {
+		// WHERE id = ? AND totp_last_step < ?, evaluated atomically
+		step := args[len(args)-1].(int64)
+		if args[len(args)-2] != u.user.ID.String() || u.lastStep >= step {
+			return &sqltest.Result{}, nil
+		}
+		u.lastStep = step
+		return &sqltest.Result{RowsAffected: 1}, nil
+	}
+	if strings.HasPrefix(query, `SELECT * FROM "sessions"`) && !u.sessionCreatedAt.IsZero() {
+		return &sqltest.Result{
+			Columns: []string{"id", "user_id", "created_at"},
+			Rows:    [][]driver.Value{{args[0], u.user.ID.String(), u.sessionCreatedAt}},
+		}, nil
+	}
+	if strings.HasPrefix(query, `UPDATE "users"`) && strings.Contains(query, `"totp_enabled"=`) {
+		u.disabled = true
+	}
+	return &sqltest.Result{RowsAffected: 1}, nil
+}
+
+func newTestMFAService(t *testing.T) (*MFAService, *totpUser) {
+	t.Helper()
+	cipher, err := encryption.NewCipher(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := cipher.Encrypt(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &totpUser{
+		user:   models.User{ID: uuid.New(), TOTPSecret: encrypted, TOTPEnabled: true},
+		secret: secret,
+	}
+	db := sqltest.Open(t, u.handle)
+	service := NewMFAService(
+		repositories.NewRecoveryCodeRepository(db),
+		NewUserService(repositories.NewUserRepository(db, pagination.NewSigner([]byte("key")))),
+		nil,
+		nil,
+		NewSessionService(repositories.NewSessionRepository(db), nil),
+		nil,
+		cipher,
+		"Reddit Clone",
+	)
+	return service, u
+}
+
+func TestCheckTOTPRefusesConcurrentReplay(t *testing.T) {
+	service, u := newTestMFAService(t)
+	code, err := totp.Code(u.secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every request loaded the user before any of them recorded the step
+	const requests = 10
+	errs := make(chan error, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user := u.user
+			_, err := service.RegenerateRecoveryCodes(context.Background(), &user, code)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	accepted := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			accepted++
+		case !errors.Is(err, ErrInvalidMFACode):
+			t.Fatalf("RegenerateRecoveryCodes: %v", err)
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("code was accepted %d times, want once", accepted)
+	}
+}
+
+func TestCheckTOTPRefusesUsedStep(t *testing.T) {
+	service, u := newTestMFAService(t)
+	step := totp.Step(time.Now())
+	code, err := totp.Code(u.secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another request already used this step, after user was loaded
+	u.lastStep = step
+	user := u.user
+	if err := service.checkTOTP(context.Background(), &user, code); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("err = %v, want ErrInvalidMFACode", err)
+	}
+
+	u.lastStep = step - 1
+	if err := service.checkTOTP(context.Background(), &user, code); err != nil {
+		t.Fatalf("checkTOTP: %v", err)
+	}
+	if user.TOTPLastStep != step || u.lastStep != step {
+		t.Errorf("step = %d, stored %d, want %d", user.TOTPLastStep, u.lastStep, step)
+	}
+}
+
+func TestDisableConfirmsIdentity(t *testing.T) {
+	googleID := "google-user"
+	tests := []struct {
+		name             string
+		password         string
+		googleID         *string
+		sessionCreatedAt time.Duration // Before now, zero for no session
+		wantErr          error
+	}{
+		{"password", "correct horse", nil, 0, nil},
+		{"wrong password", "wrong horse", nil, 0, ErrInvalidPassword},
+		{"missing password", "", nil, 0, ErrInvalidPassword},
+		{"google user signed in recently", "", &googleID, time.Minute, nil},
+		{"google user signed in long ago", "", &googleID, time.Hour, ErrReauthRequired},
+		{"google user without session", "", &googleID, 0, ErrReauthRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, u := newTestMFAService(t)
+			if tt.sessionCreatedAt != 0 {
+				u.sessionCreatedAt = time.Now().Add(-tt.sessionCreatedAt)
+			}
+			user := u.user
+			user.GoogleID = tt.googleID
+			if tt.googleID == nil {
+				user.Password = "correct horse"
+				if err := user.HashPassword(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			code, err := totp.Code(u.secret, totp.Step(time.Now()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = service.Disable(context.Background(), &user, uuid.New(), dto.MFADisableRequest{Password: tt.password, Code: code})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if u.disabled != (tt.wantErr == nil) {
+				t.Errorf("disabled = %t, want %t", u.disabled, tt.wantErr == nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/google/uuid"
+)
+
+var ErrCannotMuteSelf = errors.New("you cannot mute yourself")
+
+// MuteService manages the users a user has muted. A mute only hides the
+// muted user's content and notifications from the muter. It has none of a
+// block's side effects: follows are kept and the muted user can still
+// follow, message or mention the muter.
+type MuteService struct {
+	repo        *repositories.MuteRepository
+	userService *UserService
+}
+
+func NewMuteService(repo *repositories.MuteRepository, userService *UserService) *MuteService {
+	return &MuteService{
+		repo:        repo,
+		userService: userService,
+	}
+}
+
+// Mute makes muter mute the user with the given handler. Muting someone
+// twice is not an error.
+func (s *MuteService) Mute(ctx context.Context, muter *models.User, handler string) error {
+	target, err := s.userService.FindOne(ctx, map[string]any{"handler": handler})
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrUserNotFound
+	}
+	if target.ID == muter.ID {
+		return ErrCannotMuteSelf
+	}
+	_, err = s.repo.Create(ctx, muter.ID, target.ID)
+	return err
+}
+
+// Unmute lifts muter's mute on the user with the given handler
+func (s *MuteService) Unmute(ctx context.Context, muter *models.User, handler string) error {
+	target, err := s.userService.FindOne(ctx, map[string]any{"handler": handler})
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrUserNotFound
+	}
+	_, err = s.repo.Delete(ctx, muter.ID, target.ID)
+	return err
+}
+
+// List returns one page of the users muterID muted
+func (s *MuteService) List(ctx context.Context, muterID uuid.UUID, params pagination.Params) (*pagination.Page[types.MuteListEntry], error) {
+	return s.repo.FindPage(ctx, muterID, params)
+}
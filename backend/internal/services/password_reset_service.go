@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/dfanso/reddit-clone/pkg/mailer"
+)
+
+const (
+	passwordResetTokenBytes   = 32
+	passwordResetTokenTTL     = 30 * time.Minute
+	passwordResetResendDelay  = time.Minute
+	passwordResetWindow       = time.Hour // At most loginFailureWindow, after which counts are pruned
+	passwordResetMaxPerEmail  = 5         // per window
+	passwordResetMaxPerIP     = 20        // per window
+	passwordResetSendDeadline = 30 * time.Second
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+	ErrResetThrottled    = errors.New("too many password reset requests, try again later")
+)
+
+// PasswordResetService handles the forgot/reset password flow
+type PasswordResetService struct {
+	repo        *repositories.PasswordResetRepository
+	userService *UserService
+	authService *AuthService
+	requests    LoginAttemptStore // Counts reset requests per email and per IP
+	mailer      mailer.Mailer
+	frontendURL string
+}
+
+func NewPasswordResetService(repo *repositories.PasswordResetRepository, userService *UserService, authService *AuthService, requests LoginAttemptStore, mailer mailer.Mailer, frontendURL string) *PasswordResetService {
+	return &PasswordResetService{
+		repo:        repo,
+		userService: userService,
+		authService: authService,
+		requests:    requests,
+		mailer:      mailer,
+		frontendURL: frontendURL,
+	}
+}
+
+// ForgotPassword emails a reset link to the user. Requests are throttled
+// per email and per IP whether or not the email is registered, and the
+// link is sent after returning, so neither the response nor its timing
+// tells callers which emails are registered.
+func (s *PasswordResetService) ForgotPassword(ctx context.Context, email, ip string) error {
+	if err := s.throttle(ctx, email, ip); err != nil {
+		return err
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passwordResetSendDeadline)
+		defer cancel()
+		if err := s.sendResetLink(ctx, email); err != nil {
+			log.Printf("Failed to issue password reset: %v", err)
+		}
+	}()
+	return nil
+}
+
+// throttle counts the request against the email and the IP and returns
+// ErrResetThrottled when either is over its limit
+func (s *PasswordResetService) throttle(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	emailKey := "reset-email:" + strings.ToLower(strings.TrimSpace(email))
+	last, err := s.requests.Get(ctx, emailKey)
+	if err != nil {
+		return err
+	}
+	if last != nil && now.Sub(last.LastFailureAt) < passwordResetResendDelay {
+		return ErrResetThrottled
+	}
+
+	limits := map[string]int{emailKey: passwordResetMaxPerEmail, "reset-ip:" + ip: passwordResetMaxPerIP}
+	for key, limit := range limits {
+		attempt, err := s.requests.RecordFailure(ctx, key, now, passwordResetWindow)
+		if err != nil {
+			return err
+		}
+		if attempt.Failures > limit {
+			return ErrResetThrottled
+		}
+	}
+	return nil
+}
+
+// sendResetLink stores a new reset token for the user with the email and
+// mails them the link. Unknown emails are skipped.
+func (s *PasswordResetService) sendResetLink(ctx context.Context, email string) error {
+	user, err := s.userService.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	token, err := auth.GenerateOpaqueToken(passwordResetTokenBytes)
+	if err != nil {
+		return errors.New("failed to generate reset token")
+	}
+	err = s.repo.Create(ctx, &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTokenTTL),
+	})
+	if err != nil {
+		return errors.New("failed to store reset token")
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.frontendURL, url.QueryEscape(token))
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %d minutes and can only be used once.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.Name, int(passwordResetTokenTTL.Minutes()), link),
+	})
+}
+
+// ResetPassword consumes the reset token, sets the new password and signs
+// the user out everywhere
+func (s *PasswordResetService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	resetToken, err := s.repo.FindByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		return err
+	}
+	if resetToken == nil {
+		return ErrInvalidResetToken
+	}
+
+	used, err := s.repo.MarkUsed(ctx, resetToken.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userService.GetByID(ctx, resetToken.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	user.Password = newPassword
+	if err := user.HashPassword(); err != nil {
+		return errors.New("failed to hash password")
+	}
+	if err := s.userService.UpdateColumns(ctx, user, "password"); err != nil {
+		return errors.New("failed to update password")
+	}
+
+	return s.authService.LogoutAll(ctx, user.ID)
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/pkg/imaging"
+	"github.com/dfanso/reddit-clone/pkg/storage"
+)
+
+// ProfileImageKind names one of the images on a user profile
+type ProfileImageKind string
+
+const (
+	ProfileImageAvatar ProfileImageKind = "avatar"
+	ProfileImageBanner ProfileImageKind = "banner"
+)
+
+// MaxProfileImageBytes is the largest upload accepted for any profile image
+const MaxProfileImageBytes = 8 << 20
+
+// profileImageOptions sets the limits and output size of each image kind
+var profileImageOptions = map[ProfileImageKind]imaging.Options{
+	ProfileImageAvatar: {MaxBytes: 5 << 20, MaxPixels: 40_000_000, Width: 256, Height: 256},
+	ProfileImageBanner: {MaxBytes: MaxProfileImageBytes, MaxPixels: 40_000_000, Width: 1920, Height: 384},
+}
+
+var ErrUnknownImageKind = errors.New("unknown profile image kind")
+
+// ProfileImageService processes avatar and banner uploads and keeps them
+// in the blob store
+type ProfileImageService struct {
+	userService *UserService
+	store       storage.BlobStore
+}
+
+func NewProfileImageService(userService *UserService, store storage.BlobStore) *ProfileImageService {
+	return &ProfileImageService{
+		userService: userService,
+		store:       store,
+	}
+}
+
+// Upload validates and resizes the image, stores it under a fresh key and
+// points the user's avatar or banner at it. The previous image is deleted
+// when it was one of ours.
+func (s *ProfileImageService) Upload(ctx context.Context, user *models.User, kind ProfileImageKind, data []byte) (*models.User, error) {
+	opts, ok := profileImageOptions[kind]
+	if !ok {
+		return nil, ErrUnknownImageKind
+	}
+	image, err := imaging.Process(data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// A fresh key per upload so caches never serve the old image
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%ss/%s/%s%s", kind, user.ID, hex.EncodeToString(suffix), image.Extension)
+	if err := s.store.Put(ctx, key, image.Data, image.ContentType); err != nil {
+		return nil, fmt.Errorf("failed to store image: %v", err)
+	}
+
+	field, column := &user.Avatar, "avatar"
+	if kind == ProfileImageBanner {
+		field, column = &user.Banner, "banner"
+	}
+	previous := *field
+	*field = s.store.URL(key)
+	if err := s.userService.UpdateColumns(ctx, user, column); err != nil {
+		*field = previous
+		s.deleteBlob(ctx, key)
+		return nil, errors.New("failed to update user")
+	}
+
+	if oldKey, ok := s.store.KeyFromURL(previous); ok {
+		s.deleteBlob(ctx, oldKey)
+	}
+	return user, nil
+}
+
+// deleteBlob removes a blob that is no longer referenced. Failures only
+// leave an orphan behind, so they are logged rather than returned.
+func (s *ProfileImageService) deleteBlob(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil {
+		log.Printf("Failed to delete blob %s: %v", key, err)
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/google/uuid"
+)
+
+// RevocationService keeps track of access tokens that must be rejected
+// before they expire
+type RevocationService struct {
+	repo           *repositories.RevocationRepository
+	accessTokenTTL time.Duration
+}
+
+func NewRevocationService(repo *repositories.RevocationRepository, accessTokenTTL time.Duration) *RevocationService {
+	return &RevocationService{
+		repo:           repo,
+		accessTokenTTL: accessTokenTTL,
+	}
+}
+
+// RevokeToken revokes a single access token
+func (s *RevocationService) RevokeToken(ctx context.Context, claims *auth.JWTClaims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return errors.New("token cannot be revoked")
+	}
+	return s.repo.RevokeToken(ctx, &models.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+}
+
+// RevokeAllForUser revokes every access token issued to the user so far.
+// iat has second precision, so the cutoff is the start of the next second
+// and also covers tokens issued earlier in the current one.
+func (s *RevocationService) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	cutoff := time.Now().Truncate(time.Second).Add(time.Second)
+	return s.repo.RevokeUserTokens(ctx, &models.UserTokenRevocation{
+		UserID:        userID,
+		RevokedBefore: cutoff,
+		ExpiresAt:     cutoff.Add(s.accessTokenTTL),
+	})
+}
+
+// IsRevoked reports whether the token was revoked on its own or by a
+// revoke-all for its user
+func (s *RevocationService) IsRevoked(ctx context.Context, claims *auth.JWTClaims) (bool, error) {
+	revoked, err := s.repo.IsTokenRevoked(ctx, claims.ID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	revocation, err := s.repo.FindUserRevocation(ctx, claims.UserID)
+	if err != nil || revocation == nil {
+		return false, err
+	}
+	if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(revocation.RevokedBefore) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// StartPruner deletes expired revocation entries every interval until ctx
+// is cancelled
+func (s *RevocationService) StartPruner(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pruned, err := s.repo.PruneExpired(ctx, time.Now())
+				if err != nil {
+					log.Printf("Failed to prune revoked tokens: %v", err)
+					continue
+				}
+				if pruned > 0 {
+					log.Printf("Pruned %d expired token revocations", pruned)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/internal/sqltest"
+	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestRevokeAllForUserCutoff(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	var revokedBefore time.Time
+	db := sqltest.Open(t, func(query string, args []driver.Value) (*sqltest.Result, error) {
+		switch {
+		case strings.HasPrefix(query, `INSERT INTO "user_token_revocations"`):
+			revokedBefore = args[1].(time.Time)
+		case strings.HasPrefix(query, `SELECT count(*) FROM "revoked_tokens"`):
+			return &sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(0)}}}, nil
+		case strings.HasPrefix(query, `SELECT * FROM "user_token_revocations"`):
+			return &sqltest.Result{
+				Columns: []string{"user_id", "revoked_before", "expires_at"},
+				Rows:    [][]driver.Value{{userID.String(), revokedBefore, revokedBefore.Add(time.Hour)}},
+			}, nil
+		}
+		return &sqltest.Result{RowsAffected: 1}, nil
+	})
+	service := NewRevocationService(repositories.NewRevocationRepository(db), time.Hour)
+
+	revokedAt := time.Now()
+	if err := service.RevokeAllForUser(ctx, userID); err != nil {
+		t.Fatalf("RevokeAllForUser: %v", err)
+	}
+	if !revokedBefore.Equal(revokedBefore.Truncate(time.Second)) || !revokedBefore.After(revokedAt) {
+		t.Fatalf("cutoff = %v, want the second after %v", revokedBefore, revokedAt)
+	}
+
+	tests := []struct {
+		name        string
+		issuedAt    *jwt.NumericDate
+		wantRevoked bool
+	}{
+		{"second before the revoke", jwt.NewNumericDate(revokedBefore.Add(-2 * time.Second)), true},
+		{"same second as the revoke", jwt.NewNumericDate(revokedBefore.Add(-time.Second)), true},
+		{"second after the revoke", jwt.NewNumericDate(revokedBefore), false},
+		{"no iat", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &auth.JWTClaims{
+				UserID:           userID,
+				RegisteredClaims: jwt.RegisteredClaims{ID: uuid.NewString(), IssuedAt: tt.issuedAt},
+			}
+			revoked, err := service.IsRevoked(ctx, claims)
+			if err != nil {
+				t.Fatalf("IsRevoked: %v", err)
+			}
+			if revoked != tt.wantRevoked {
+				t.Errorf("revoked = %t, want %t", revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
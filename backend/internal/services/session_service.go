@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// reauthWindow is how recently a session must have been signed in to
+// stand in for a password confirmation
+const reauthWindow = 10 * time.Minute
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrReauthRequired  = errors.New("sign in again to confirm it is you")
+)
+
+// SessionService lists and ends sessions, and tracks when each was last
+// used. Last-seen times are buffered in memory and written in batches so
+// authenticated requests don't each cost a write.
+type SessionService struct {
+	repo         *repositories.SessionRepository
+	tokenService *TokenService
+
+	mu       sync.Mutex
+	lastSeen map[uuid.UUID]time.Time
+}
+
+func NewSessionService(repo *repositories.SessionRepository, tokenService *TokenService) *SessionService {
+	return &SessionService{
+		repo:         repo,
+		tokenService: tokenService,
+		lastSeen:     map[uuid.UUID]time.Time{},
+	}
+}
+
+func (s *SessionService) List(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	return s.repo.FindByUserID(ctx, userID)
+}
+
+// Get returns one of the user's sessions
+func (s *SessionService) Get(ctx context.Context, userID, id uuid.UUID) (*models.Session, error) {
+	session, err := s.repo.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// ConfirmIdentity checks that the caller of a sensitive action is the
+// account owner. The caller gives their password. Users who signed up with
+// Google never chose one, so for them a session signed in within
+// reauthWindow, such as right after a fresh Google sign-in, is accepted
+// instead.
+func (s *SessionService) ConfirmIdentity(ctx context.Context, user *models.User, sessionID uuid.UUID, password string) error {
+	if password != "" || user.GoogleID == nil {
+		if err := user.ComparePassword(password); err != nil {
+			return ErrInvalidPassword
+		}
+		return nil
+	}
+
+	session, err := s.Get(ctx, user.ID, sessionID)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return ErrReauthRequired
+		}
+		return err
+	}
+	if time.Since(session.CreatedAt) > reauthWindow {
+		return ErrReauthRequired
+	}
+	return nil
+}
+
+// Exists reports whether the session is still active
+func (s *SessionService) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	return s.repo.Exists(ctx, id)
+}
+
+// Touch records that the session was just used. It is written out by the
+// next flush.
+func (s *SessionService) Touch(id uuid.UUID) {
+	s.mu.Lock()
+	s.lastSeen[id] = time.Now()
+	s.mu.Unlock()
+}
+
+// Revoke ends one of the user's sessions along with its refresh tokens
+func (s *SessionService) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	deleted, err := s.repo.Delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSessionNotFound
+	}
+	return s.tokenService.RevokeSession(ctx, id)
+}
+
+// RevokeAll ends every session of the user
+func (s *SessionService) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	if err := s.repo.DeleteAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.tokenService.RevokeAll(ctx, userID)
+}
+
+// RevokeOthers ends every session of the user except keepID. Access tokens
+// of the ended sessions stop working right away, since the auth middleware
+// checks their session still exists.
+func (s *SessionService) RevokeOthers(ctx context.Context, userID, keepID uuid.UUID) error {
+	if err := s.repo.DeleteAllForUserExcept(ctx, userID, keepID); err != nil {
+		return err
+	}
+	return s.tokenService.RevokeAllExcept(ctx, userID, keepID)
+}
+
+// StartFlusher writes buffered last-seen times every interval until ctx is
+// cancelled
+func (s *SessionService) StartFlusher(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.flush(ctx)
+			}
+		}
+	}()
+}
+
+func (s *SessionService) flush(ctx context.Context) {
+	s.mu.Lock()
+	batch := s.lastSeen
+	s.lastSeen = map[uuid.UUID]time.Time{}
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	if err := s.repo.UpdateLastSeen(ctx, batch); err != nil {
+		log.Printf("Failed to update session last-seen times: %v", err)
+	}
+}
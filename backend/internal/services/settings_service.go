@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/pkg/mergepatch"
+	"github.com/google/uuid"
+)
+
+// InvalidSettingsError is returned when a patch produces a document that
+// doesn't match the settings schema
+type InvalidSettingsError struct {
+	Err error
+}
+
+func (e *InvalidSettingsError) Error() string {
+	return fmt.Sprintf("invalid settings: %v", e.Err)
+}
+
+func (e *InvalidSettingsError) Unwrap() error {
+	return e.Err
+}
+
+// SettingsService reads and patches per-user settings
+type SettingsService struct {
+	repo *repositories.UserSettingsRepository
+}
+
+func NewSettingsService(repo *repositories.UserSettingsRepository) *SettingsService {
+	return &SettingsService{
+		repo: repo,
+	}
+}
+
+// Get returns the user's settings, with defaults for anything not stored
+func (s *SettingsService) Get(ctx context.Context, userID uuid.UUID) (*models.SettingsDocument, error) {
+	stored, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		doc := models.DefaultSettings()
+		return &doc, nil
+	}
+	doc, err := models.DecodeSettings(stored.Settings, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode stored settings: %v", err)
+	}
+	return &doc, nil
+}
+
+// Patch applies a JSON merge patch (RFC 7396) to the user's settings and
+// returns the result. Setting a field to null resets it to its default,
+// and the version is managed by the server.
+func (s *SettingsService) Patch(ctx context.Context, userID uuid.UUID, patch []byte) (*models.SettingsDocument, error) {
+	var result models.SettingsDocument
+	err := s.repo.Update(ctx, userID, func(current json.RawMessage) (json.RawMessage, error) {
+		merged, err := mergepatch.Apply(current, patch)
+		if err != nil {
+			return nil, &InvalidSettingsError{Err: err}
+		}
+
+		// Check the merged document against the schema before storing it
+		doc, err := models.DecodeSettings(merged, true)
+		if err != nil {
+			return nil, &InvalidSettingsError{Err: err}
+		}
+		if err := doc.Validate(); err != nil {
+			return nil, &InvalidSettingsError{Err: err}
+		}
+		result = doc
+
+		// Store the normalized document so every row carries its version
+		return json.Marshal(doc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
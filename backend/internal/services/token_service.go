@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	dto "github.com/dfanso/reddit-clone/internal/dtos"
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/google/uuid"
+)
+
+// refreshTokenBytes is the amount of randomness in a refresh token
+const refreshTokenBytes = 32
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, all sessions from this login were revoked")
+)
+
+// TokenService issues access tokens and manages refresh token rotation
+type TokenService struct {
+	repo            *repositories.RefreshTokenRepository
+	sessionRepo     *repositories.SessionRepository
+	userService     *UserService
+	jwtManager      *auth.JWTManager
+	refreshTokenTTL time.Duration
+}
+
+func NewTokenService(repo *repositories.RefreshTokenRepository, sessionRepo *repositories.SessionRepository, userService *UserService, jwtManager *auth.JWTManager, refreshTokenTTL time.Duration) *TokenService {
+	return &TokenService{
+		repo:            repo,
+		sessionRepo:     sessionRepo,
+		userService:     userService,
+		jwtManager:      jwtManager,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+// IssueTokens starts a new session for the user and returns a fresh
+// access/refresh token pair for it. Banned and suspended users are refused.
+func (s *TokenService) IssueTokens(ctx context.Context, user *models.User, client dto.ClientInfo) (*dto.TokenResponse, error) {
+	if err := checkAccountAccess(user); err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		UserID:     user.ID,
+		UserAgent:  truncate(client.UserAgent, 255),
+		IP:         truncate(client.IP, 45),
+		LastSeenAt: time.Now(),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, errors.New("failed to create session")
+	}
+
+	refreshToken, record, err := s.newRefreshToken(user.ID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, record); err != nil {
+		return nil, errors.New("failed to store refresh token")
+	}
+	return s.tokenResponse(user, record.FamilyID, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented
+// token is rotated out; presenting it again revokes its whole family.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*dto.TokenResponse, error) {
+	current, err := s.repo.FindByHash(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+	if current.RotatedAt != nil || current.RevokedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, current)
+	}
+	if !now.Before(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// A deleted session can no longer be refreshed
+	active, err := s.sessionRepo.Exists(ctx, current.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		_ = s.repo.RevokeFamily(ctx, current.FamilyID)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userService.GetByID(ctx, current.UserID)
+	if err != nil {
+		_ = s.repo.RevokeFamily(ctx, current.FamilyID)
+		return nil, ErrInvalidRefreshToken
+	}
+	if err := checkAccountAccess(user); err != nil {
+		_ = s.repo.RevokeFamily(ctx, current.FamilyID)
+		return nil, err
+	}
+
+	nextToken, next, err := s.newRefreshToken(user.ID, current.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.repo.Rotate(ctx, current, next)
+	if err != nil {
+		return nil, errors.New("failed to rotate refresh token")
+	}
+	if !rotated {
+		// Another request rotated this token first
+		return nil, s.revokeReusedFamily(ctx, current)
+	}
+
+	if err := s.sessionRepo.UpdateLastSeen(ctx, map[uuid.UUID]time.Time{current.FamilyID: now}); err != nil {
+		return nil, errors.New("failed to update session")
+	}
+
+	return s.tokenResponse(user, current.FamilyID, nextToken)
+}
+
+// RevokeSession revokes the refresh token family behind one session
+func (s *TokenService) RevokeSession(ctx context.Context, familyID uuid.UUID) error {
+	return s.repo.RevokeFamily(ctx, familyID)
+}
+
+// RevokeAll revokes every refresh token of the user
+func (s *TokenService) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	return s.repo.RevokeAllForUser(ctx, userID)
+}
+
+// RevokeAllExcept revokes every refresh token of the user outside the
+// session with the given ID
+func (s *TokenService) RevokeAllExcept(ctx context.Context, userID, sessionID uuid.UUID) error {
+	return s.repo.RevokeAllForUserExcept(ctx, userID, sessionID)
+}
+
+func (s *TokenService) revokeReusedFamily(ctx context.Context, token *models.RefreshToken) error {
+	if err := s.repo.RevokeFamily(ctx, token.FamilyID); err != nil {
+		return errors.New("failed to revoke refresh tokens")
+	}
+	return ErrRefreshTokenReused
+}
+
+func (s *TokenService) newRefreshToken(userID, familyID uuid.UUID) (string, *models.RefreshToken, error) {
+	token, err := auth.GenerateOpaqueToken(refreshTokenBytes)
+	if err != nil {
+		return "", nil, errors.New("failed to generate refresh token")
+	}
+	return token, &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+	}, nil
+}
+
+func (s *TokenService) tokenResponse(user *models.User, familyID uuid.UUID, refreshToken string) (*dto.TokenResponse, error) {
+	accessToken, err := s.jwtManager.GenerateToken(user.ID, string(user.Role), familyID)
+	if err != nil {
+		return nil, errors.New("failed to generate access token")
+	}
+	return &dto.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.jwtManager.AccessTokenTTL().Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
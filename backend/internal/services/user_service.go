@@ -3,7 +3,6 @@ package services
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/google/uuid"
 
@@ -11,11 +10,11 @@ import (
 	"github.com/dfanso/reddit-clone/internal/models"
 	"github.com/dfanso/reddit-clone/internal/repositories"
 	"github.com/dfanso/reddit-clone/internal/types"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
 )
 
 type UserService struct {
 	repo *repositories.UserRepository
-	types.UserPaginationResult
 }
 
 func NewUserService(repo *repositories.UserRepository) *UserService {
@@ -28,31 +27,60 @@ func (s *UserService) FindOne(ctx context.Context, filter interface{}) (*models.
 	return s.repo.FindOne(ctx, filter)
 }
 
+// FindByEmail returns the user with the email, ignoring case, or nil if
+// there is none
+func (s *UserService) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.repo.FindByEmail(ctx, email)
+}
+
 func (s *UserService) GetAll(ctx context.Context) ([]models.User, error) {
 	return s.repo.FindAll(ctx)
 }
 
-func (s *UserService) FindPaginated(ctx context.Context, query interface{}, page int, limit int) (*types.UserPaginationResult, error) {
-	return s.repo.FindPaginated(ctx, query, page, limit)
+func (s *UserService) FindPage(ctx context.Context, filter types.UserFilter, params pagination.Params) (*pagination.Page[models.User], error) {
+	return s.repo.FindPage(ctx, filter, params)
 }
 
 func (s *UserService) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	return s.repo.FindByID(ctx, id)
 }
 
+// GetPublicByHandler returns the user with the given handler if their
+// profile is public, meaning signup is complete and they are not banned
+func (s *UserService) GetPublicByHandler(ctx context.Context, handler string) (*models.User, error) {
+	user, err := s.repo.FindOne(ctx, map[string]any{"handler": handler})
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Stage != models.StageCompleted || user.Status == models.StatusBanned {
+		return nil, nil
+	}
+	return user, nil
+}
+
 func (s *UserService) Create(ctx context.Context, user *models.User) (*models.User, error) {
 	return s.repo.Create(ctx, user)
 }
 
-func (s *UserService) Update(ctx context.Context, user *models.User) error {
-	return s.repo.Update(ctx, user)
+// UpdateColumns writes the given columns of user, leaving the rest of the
+// row, including the moderation columns, as stored
+func (s *UserService) UpdateColumns(ctx context.Context, user *models.User, columns ...string) error {
+	return s.repo.UpdateColumns(ctx, user, columns...)
 }
 
-func (s *UserService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id)
+// SetVerificationStatus marks the user verified or unverified unless they
+// are banned
+func (s *UserService) SetVerificationStatus(ctx context.Context, user *models.User, status models.Status) error {
+	return s.repo.SetVerificationStatus(ctx, user, status)
 }
 
-// RegisterUser creates a new user with the provided details and returns the user with formatted username
+// AdvanceTOTPStep records an accepted TOTP step. It reports false if the
+// step, or a later one, was already used.
+func (s *UserService) AdvanceTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	return s.repo.AdvanceTOTPStep(ctx, id, step)
+}
+
+// RegisterUser creates a new user with the provided details
 func (s *UserService) RegisterUser(ctx context.Context, req dto.RegisterRequest) (*models.User, error) {
 	// Create user model
 	user := &models.User{
@@ -66,8 +94,7 @@ func (s *UserService) RegisterUser(ctx context.Context, req dto.RegisterRequest)
 	}
 
 	// Check if email already exists
-	filter := map[string]any{"email": req.Email}
-	existingUser, err := s.FindOne(ctx, filter)
+	existingUser, err := s.FindByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, errors.New("Error checking existing user")
 	}
@@ -76,8 +103,7 @@ func (s *UserService) RegisterUser(ctx context.Context, req dto.RegisterRequest)
 	}
 
 	// Check if username already exists
-	filter = map[string]any{"handler": req.Username}
-	existingUser, err = s.FindOne(ctx, filter)
+	existingUser, err = s.FindOne(ctx, map[string]any{"handler": req.Username})
 	if err != nil {
 		return nil, errors.New("Error checking existing username")
 	}
@@ -96,8 +122,65 @@ func (s *UserService) RegisterUser(ctx context.Context, req dto.RegisterRequest)
 		return nil, errors.New("failed to create user")
 	}
 
-	// Format the username with the "u/" prefix for the frontend
-	createdUser.Handler = fmt.Sprintf("u/%s", createdUser.Handler)
-
 	return createdUser, nil
 }
+
+// UpdateProfile applies the fields set in the request to the user's profile
+func (s *UserService) UpdateProfile(ctx context.Context, user *models.User, req dto.UpdateProfileRequest) (*models.User, error) {
+	req.ApplyTo(user)
+	if err := s.repo.UpdateColumns(ctx, user, "name", "description", "avatar", "banner"); err != nil {
+		return nil, errors.New("failed to update user")
+	}
+	return user, nil
+}
+
+// UpdatePrivileged applies the role, status, stage and karma fields set in
+// the request. Status only moves between verified and unverified; bans
+// belong to moderation.
+func (s *UserService) UpdatePrivileged(ctx context.Context, user *models.User, req dto.UpdateUserRequest) error {
+	status := req.Status
+	req.Status = nil
+	req.ApplyPrivilegedTo(user)
+	if err := s.repo.UpdateColumns(ctx, user, "role", "stage", "post_karma", "comment_karma"); err != nil {
+		return errors.New("failed to update user")
+	}
+	if status != nil && *status != user.Status {
+		if err := s.repo.SetVerificationStatus(ctx, user, *status); err != nil {
+			return errors.New("failed to update user")
+		}
+	}
+	return nil
+}
+
+var ErrHandlerTaken = errors.New("Username already taken")
+
+// CompleteSignup stores the final signup details and moves the user to the
+// completed stage
+func (s *UserService) CompleteSignup(ctx context.Context, user *models.User, req dto.CompleteSignupRequest) (*models.User, error) {
+	// Check the handler is free, unless the user keeps their own
+	if req.Handler != user.Handler {
+		existingUser, err := s.FindOne(ctx, map[string]any{"handler": req.Handler})
+		if err != nil {
+			return nil, errors.New("Error checking existing username")
+		}
+		if existingUser != nil {
+			return nil, ErrHandlerTaken
+		}
+	}
+
+	if err := user.TransitionTo(models.StageCompleted); err != nil {
+		return nil, err
+	}
+
+	user.Handler = req.Handler
+	user.Name = req.Name
+	user.Description = req.Description
+	if req.Avatar != "" {
+		user.Avatar = req.Avatar
+	}
+
+	if err := s.repo.UpdateColumns(ctx, user, "handler", "name", "description", "avatar", "stage"); err != nil {
+		return nil, errors.New("failed to update user")
+	}
+	return user, nil
+}
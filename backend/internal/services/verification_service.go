@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/pkg/mailer"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	verificationCodeDigits  = 6
+	verificationCodeTTL     = 10 * time.Minute
+	verificationMaxAttempts = 5
+	verificationResendDelay = time.Minute
+	verificationSendWindow  = time.Hour
+	verificationMaxSends    = 5 // per send window
+)
+
+var (
+	ErrInvalidVerificationCode = errors.New("invalid or expired verification code")
+	ErrTooManyAttempts         = errors.New("too many failed attempts, request a new code")
+	ErrResendThrottled         = errors.New("please wait before requesting another code")
+)
+
+// VerificationService sends and checks email verification codes
+type VerificationService struct {
+	repo        *repositories.VerificationCodeRepository
+	userService *UserService
+	mailer      mailer.Mailer
+}
+
+func NewVerificationService(repo *repositories.VerificationCodeRepository, userService *UserService, mailer mailer.Mailer) *VerificationService {
+	return &VerificationService{
+		repo:        repo,
+		userService: userService,
+		mailer:      mailer,
+	}
+}
+
+// SendCode generates a new code for the user, replacing any pending one,
+// and emails it. Sends are throttled per user.
+func (s *VerificationService) SendCode(ctx context.Context, user *models.User) error {
+	existing, err := s.repo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	sendCount, windowStartedAt := 1, now
+	if existing != nil {
+		if now.Sub(existing.SentAt) < verificationResendDelay {
+			return ErrResendThrottled
+		}
+		if now.Sub(existing.WindowStartedAt) < verificationSendWindow {
+			sendCount, windowStartedAt = existing.SendCount+1, existing.WindowStartedAt
+		}
+		if sendCount > verificationMaxSends {
+			return ErrResendThrottled
+		}
+	}
+
+	code, err := generateNumericCode(verificationCodeDigits)
+	if err != nil {
+		return errors.New("failed to generate verification code")
+	}
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash verification code")
+	}
+
+	err = s.repo.Upsert(ctx, &models.VerificationCode{
+		UserID:          user.ID,
+		CodeHash:        string(codeHash),
+		ExpiresAt:       now.Add(verificationCodeTTL),
+		Attempts:        0,
+		SentAt:          now,
+		SendCount:       sendCount,
+		WindowStartedAt: windowStartedAt,
+	})
+	if err != nil {
+		return errors.New("failed to store verification code")
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your verification code",
+		Body: fmt.Sprintf("Hi %s,\n\nYour verification code is %s. It expires in %d minutes.\n\nIf you did not sign up, you can ignore this email.\n",
+			user.Name, code, int(verificationCodeTTL.Minutes())),
+	})
+}
+
+// Resend emails a new code to an unverified user. Unknown and already
+// verified emails succeed silently so the endpoint can't be used to probe
+// for accounts.
+func (s *VerificationService) Resend(ctx context.Context, email string) error {
+	user, err := s.userService.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Stage != models.StageEmailVerification {
+		return nil
+	}
+	return s.SendCode(ctx, user)
+}
+
+// Verify checks the code and marks the user's email as verified
+func (s *VerificationService) Verify(ctx context.Context, email, code string) (*models.User, error) {
+	user, err := s.userService.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Stage != models.StageEmailVerification {
+		return nil, ErrInvalidVerificationCode
+	}
+
+	pending, err := s.repo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil || !time.Now().Before(pending.ExpiresAt) {
+		return nil, ErrInvalidVerificationCode
+	}
+
+	// Reserve the attempt before comparing, so the limit holds however many
+	// guesses arrive at once
+	reserved, err := s.repo.ReserveAttempt(ctx, pending.ID, verificationMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return nil, ErrTooManyAttempts
+	}
+	if bcrypt.CompareHashAndPassword([]byte(pending.CodeHash), []byte(code)) != nil {
+		return nil, ErrInvalidVerificationCode
+	}
+
+	// Advance the signup stage
+	if err := user.TransitionTo(models.StageEmailVerified); err != nil {
+		return nil, err
+	}
+	if err := s.userService.UpdateColumns(ctx, user, "stage"); err != nil {
+		return nil, errors.New("failed to update user")
+	}
+	if err := s.userService.SetVerificationStatus(ctx, user, models.StatusVerified); err != nil {
+		return nil, errors.New("failed to update user")
+	}
+	if err := s.repo.Delete(ctx, pending.ID); err != nil {
+		return nil, errors.New("failed to clear verification code")
+	}
+
+	return user, nil
+}
+
+// generateNumericCode returns a random zero-padded code of the given length
+func generateNumericCode(digits int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/internal/repositories"
+	"github.com/dfanso/reddit-clone/internal/sqltest"
+	"github.com/dfanso/reddit-clone/pkg/pagination"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// pendingVerification is a user waiting on an email code, with the code
+// row kept in memory by the sqltest handler
+type pendingVerification struct {
+	userID   uuid.UUID
+	codeID   uuid.UUID
+	codeHash string
+	attempts int
+	verified bool
+}
+
+func newPendingVerification(t *testing.T, code string) *pendingVerification {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pendingVerification{userID: uuid.New(), codeID: uuid.New(), codeHash: string(hash)}
+}
+
+func (p *pendingVerification) handle(query string, args []driver.Value) (*sqltest.Result, error) {
+	switch {
+	case strings.HasPrefix(query, `SELECT * FROM "users"`):
+		return &sqltest.Result{
+			Columns: []string{"id", "email", "stage", "status"},
+			Rows:    [][]driver.Value{{p.userID.String(), "jane@example.com", string(models.StageEmailVerification), string(models.StatusUnverified)}},
+		}, nil
+	case strings.HasPrefix(query, `SELECT * FROM "verification_codes"`):
+		return &sqltest.Result{
+			Columns: []string{"id", "user_id", "code_hash", "expires_at", "attempts"},
+			Rows:    [][]driver.Value{{p.codeID.String(), p.userID.String(), p.codeHash, time.Now().Add(time.Minute), int64(p.attempts)}},
+		}, nil
+	case strings.HasPrefix(query, `UPDATE "verification_codes" SET "attempts"=attempts + 1`):
+		// WHERE id = ? AND attempts < ?, evaluated atomically
+		if args[len(args)-2] != p.codeID.String() || int64(p.attempts) >= args[len(args)-1].(int64) {
+			return &sqltest.Result{}, nil
+		}
+		p.attempts++
+		return &sqltest.Result{RowsAffected: 1}, nil
+	case strings.HasPrefix(query, `UPDATE "users"`):
+		p.verified = true
+		return &sqltest.Result{RowsAffected: 1}, nil
+	}
+	return &sqltest.Result{RowsAffected: 1}, nil
+}
+
+func newTestVerificationService(t *testing.T, p *pendingVerification) *VerificationService {
+	db := sqltest.Open(t, p.handle)
+	return NewVerificationService(
+		repositories.NewVerificationCodeRepository(db),
+		NewUserService(repositories.NewUserRepository(db, pagination.NewSigner([]byte("key")))),
+		nil,
+	)
+}
+
+func TestVerifyConcurrentGuessesStayWithinAttemptLimit(t *testing.T) {
+	p := newPendingVerification(t, "123456")
+	service := newTestVerificationService(t, p)
+
+	const guesses = 4 * verificationMaxAttempts
+	errs := make(chan error, guesses)
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.Verify(context.Background(), "jane@example.com", "000000")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// A wrong code only comes back as invalid once it was compared
+	compared := 0
+	for err := range errs {
+		switch {
+		case errors.Is(err, ErrInvalidVerificationCode):
+			compared++
+		case !errors.Is(err, ErrTooManyAttempts):
+			t.Fatalf("Verify: %v", err)
+		}
+	}
+	if compared > verificationMaxAttempts {
+		t.Errorf("%d codes were compared, want at most %d", compared, verificationMaxAttempts)
+	}
+	if p.attempts != verificationMaxAttempts {
+		t.Errorf("attempts = %d, want %d", p.attempts, verificationMaxAttempts)
+	}
+}
+
+func TestVerifyRefusesCorrectCodeOnceAttemptsRunOut(t *testing.T) {
+	p := newPendingVerification(t, "123456")
+	service := newTestVerificationService(t, p)
+	ctx := context.Background()
+
+	for i := 0; i < verificationMaxAttempts; i++ {
+		if _, err := service.Verify(ctx, "jane@example.com", "000000"); !errors.Is(err, ErrInvalidVerificationCode) {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidVerificationCode", i+1, err)
+		}
+	}
+	if _, err := service.Verify(ctx, "jane@example.com", "123456"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("err = %v, want ErrTooManyAttempts", err)
+	}
+	if p.verified {
+		t.Error("user was verified after running out of attempts")
+	}
+}
+
+func TestVerifyAcceptsCorrectCode(t *testing.T) {
+	p := newPendingVerification(t, "123456")
+	service := newTestVerificationService(t, p)
+
+	user, err := service.Verify(context.Background(), "jane@example.com", "123456")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if user.Stage != models.StageEmailVerified || user.Status != models.StatusVerified || !p.verified {
+		t.Errorf("user = %s/%s, saved %t, want a saved, verified user", user.Stage, user.Status, p.verified)
+	}
+	if p.attempts != 1 {
+		t.Errorf("attempts = %d, want 1", p.attempts)
+	}
+}
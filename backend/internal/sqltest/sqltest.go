@@ -0,0 +1,138 @@
+// Package sqltest opens a *gorm.DB backed by a scripted database/sql
+// driver, so repositories and services run their real queries in tests
+// without a Postgres server. Every statement reaches a Handler with the
+// SQL and arguments GORM built for it.
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Result is a Handler's answer to one statement. Queries return Columns
+// and Rows; other statements only report RowsAffected.
+type Result struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+}
+
+// Handler answers a statement. Calls are serialized, so a handler can keep
+// its state in plain variables and each statement runs atomically, as a
+// single statement does in Postgres. A nil Result is an empty one.
+type Handler func(query string, args []driver.Value) (*Result, error)
+
+// Open returns a GORM handle that sends every statement to handler.
+// Transactions are accepted but not isolated.
+func Open(t testing.TB, handler Handler) *gorm.DB {
+	t.Helper()
+	sqlDB := sql.OpenDB(&connector{handler: handler})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+type connector struct {
+	mu      sync.Mutex
+	handler Handler
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{connector: c}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return nil
+}
+
+func (c *connector) run(query string, args []driver.NamedValue) (*Result, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, err := c.handler(query, values)
+	if result == nil {
+		result = &Result{}
+	}
+	return result, err
+}
+
+type conn struct {
+	connector *connector
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("sqltest: prepared statements are not supported")
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return tx{}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.connector.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(result.RowsAffected), nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.connector.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{result: result}, nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type rows struct {
+	result *Result
+	next   int
+}
+
+func (r *rows) Columns() []string {
+	return r.result.Columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.Rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.Rows[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,14 @@
+package types
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+)
+
+// BlockListEntry is a user in the caller's block list, with the time they
+// were blocked
+type BlockListEntry struct {
+	models.User `gorm:"embedded"`
+	BlockedAt   time.Time
+}
@@ -0,0 +1,14 @@
+package types
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+)
+
+// FollowListEntry is a user in a followers or following list, with the
+// time the follow was made
+type FollowListEntry struct {
+	models.User `gorm:"embedded"`
+	FollowedAt  time.Time
+}
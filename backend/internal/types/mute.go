@@ -0,0 +1,14 @@
+package types
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+)
+
+// MuteListEntry is a user in the caller's mute list, with the time they
+// were muted
+type MuteListEntry struct {
+	models.User `gorm:"embedded"`
+	MutedAt     time.Time
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+)
+
+// UserSort names a column the users listing can be ordered by
+type UserSort string
+
+const (
+	UserSortCreatedAt UserSort = "created_at"
+	UserSortKarma     UserSort = "karma"
+	UserSortHandler   UserSort = "handler"
+)
+
+// UserFilter narrows and orders the users listing. Zero values mean no
+// filter on that field.
+type UserFilter struct {
+	Role          models.Role
+	Status        models.Status
+	Stage         models.Stage
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Search        string // Prefix of the handler or email
+	Sort          UserSort
+	Desc          bool
+}
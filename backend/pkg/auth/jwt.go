@@ -1,97 +1,128 @@
 package auth
 
 import (
-	"crypto/ecdsa"
-	"crypto/x509"
-	"encoding/pem"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// TokenTypeMFAPending marks a token proving only the first login step
+const TokenTypeMFAPending = "mfa_pending"
+
+// mfaPendingTokenTTL bounds the time between password and 2FA code
+const mfaPendingTokenTTL = 5 * time.Minute
+
 // JWTClaims defines the structure of the JWT payload
 type JWTClaims struct {
-	UserID uuid.UUID `json:"id"`
-	Role   string    `json:"role"`
+	UserID    uuid.UUID `json:"id"`
+	Role      string    `json:"role"`
+	SessionID uuid.UUID `json:"sid"`           // Refresh token family the token was issued for
+	TokenType string    `json:"typ,omitempty"` // Empty for access tokens
 	jwt.RegisteredClaims
 }
 
 // JWTManager handles JWT generation and validation
 type JWTManager struct {
-	privateKey *ecdsa.PrivateKey
-	publicKey  *ecdsa.PublicKey
+	keyring        *Keyring
+	accessTokenTTL time.Duration
 }
 
-// NewJWTManager initializes a JWTManager with keys from files
-func NewJWTManager() (*JWTManager, error) {
-	// Read private key
-	privateKeyBytes, err := os.ReadFile("keys/private.pem")
-	if err != nil {
-		return nil, fmt.Errorf("could not read private key: %v", err)
+// NewJWTManager initializes a JWTManager that signs with the keyring's active
+// key. Access tokens it issues expire after accessTokenTTL.
+func NewJWTManager(keyring *Keyring, accessTokenTTL time.Duration) *JWTManager {
+	return &JWTManager{
+		keyring:        keyring,
+		accessTokenTTL: accessTokenTTL,
 	}
+}
+
+// JWKS returns the public keys tokens may be verified with
+func (m *JWTManager) JWKS() JWKS {
+	return m.keyring.JWKS()
+}
 
-	// Parse private key
-	block, _ := pem.Decode(privateKeyBytes)
-	if block == nil {
-		return nil, fmt.Errorf("failed to parse PEM block containing private key")
+// AccessTokenTTL returns how long issued access tokens stay valid
+func (m *JWTManager) AccessTokenTTL() time.Duration {
+	return m.accessTokenTTL
+}
+
+// GenerateToken creates a new JWT for a user session. Every token gets a
+// unique jti so it can be revoked on its own.
+func (m *JWTManager) GenerateToken(userID uuid.UUID, role string, sessionID uuid.UUID) (string, error) {
+	now := time.Now()
+	claims := &JWTClaims{
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
 	}
-	privateKey, err := x509.ParseECPrivateKey(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	return m.sign(claims)
+}
+
+// GenerateMFAPendingToken creates a short-lived token for a user who passed
+// the password check but still has to enter a 2FA code
+func (m *JWTManager) GenerateMFAPendingToken(userID uuid.UUID) (string, error) {
+	now := time.Now()
+	claims := &JWTClaims{
+		UserID:    userID,
+		TokenType: TokenTypeMFAPending,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaPendingTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
 	}
+	return m.sign(claims)
+}
 
-	// Read public key
-	publicKeyBytes, err := os.ReadFile("keys/public.pem")
+// ValidateToken verifies an access token and returns its claims
+func (m *JWTManager) ValidateToken(tokenString string) (*JWTClaims, error) {
+	claims, err := m.parse(tokenString)
 	if err != nil {
-		return nil, fmt.Errorf("could not read public key: %v", err)
+		return nil, err
 	}
-
-	// Parse public key
-	block, _ = pem.Decode(publicKeyBytes)
-	if block == nil {
-		return nil, fmt.Errorf("failed to parse PEM block containing public key")
+	if claims.TokenType != "" {
+		return nil, fmt.Errorf("not an access token")
 	}
-	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	return claims, nil
+}
+
+// ValidateMFAPendingToken verifies a token from GenerateMFAPendingToken
+func (m *JWTManager) ValidateMFAPendingToken(tokenString string) (*JWTClaims, error) {
+	claims, err := m.parse(tokenString)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse public key: %v", err)
+		return nil, err
 	}
-	ecdsaPublicKey, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("public key is not an ECDSA key")
+	if claims.TokenType != TokenTypeMFAPending {
+		return nil, fmt.Errorf("not an MFA pending token")
 	}
-
-	return &JWTManager{
-		privateKey: privateKey,
-		publicKey:  ecdsaPublicKey,
-	}, nil
+	return claims, nil
 }
 
-// GenerateToken creates a new JWT for a user
-func (m *JWTManager) GenerateToken(userID uuid.UUID, role string) (string, error) {
-	claims := &JWTClaims{
-		UserID: userID,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
-	}
+// sign signs the claims with the active key and names it in the kid header
+func (m *JWTManager) sign(claims *JWTClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
-	return token.SignedString(m.privateKey)
+	token.Header["kid"] = m.keyring.active.ID
+	return token.SignedString(m.keyring.active.Private)
 }
 
-// ValidateToken verifies a JWT and returns its claims
-func (m *JWTManager) ValidateToken(tokenString string) (*JWTClaims, error) {
+func (m *JWTManager) parse(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		kid, _ := token.Header["kid"].(string)
+		key, ok := m.keyring.PublicKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
 		}
-		return m.publicKey, nil
-	})
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}))
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %v", err)
 	}
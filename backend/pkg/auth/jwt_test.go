@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/elliptic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestValidateTokenOnlyAcceptsES256(t *testing.T) {
+	dir := t.TempDir()
+	writePrivateKey(t, dir, "key", generateKey(t, elliptic.P256()))
+	ring, err := LoadKeyring(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewJWTManager(ring, time.Hour)
+
+	claims := &JWTClaims{
+		UserID: uuid.New(),
+		Role:   "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	sign := func(method jwt.SigningMethod, key any) string {
+		t.Helper()
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = "key"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		wantOK bool
+	}{
+		{"ES256", sign(jwt.SigningMethodES256, ring.active.Private), true},
+		{"ES384", sign(jwt.SigningMethodES384, generateKey(t, elliptic.P384())), false},
+		{"HS256", sign(jwt.SigningMethodHS256, []byte("key")), false},
+		{"none", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := manager.ValidateToken(tt.token)
+			if (err == nil) != tt.wantOK {
+				t.Errorf("err = %v, want ok %t", err, tt.wantOK)
+			}
+		})
+	}
+}
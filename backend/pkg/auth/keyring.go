@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// keyIDTimeFormat prefixes generated kids so they sort by creation time
+const keyIDTimeFormat = "20060102T150405"
+
+// SigningKey is one entry of a Keyring. Private is nil for keys that are
+// only kept around to verify tokens signed before a rotation.
+type SigningKey struct {
+	ID      string
+	Private *ecdsa.PrivateKey
+	Public  *ecdsa.PublicKey
+}
+
+// Keyring holds every key tokens may be verified with and the one new
+// tokens are signed with
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// JWK is the public part of a signing key in RFC 7517 form
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeyring reads every <kid>.pem file in dir. Private keys can sign and
+// verify, public keys only verify. A public key matching a loaded private key
+// is skipped so a classic private.pem/public.pem pair yields a single key.
+// activeKID selects the signing key; when empty the newest key from
+// GenerateKeyPair wins, falling back to the last hand-named private key.
+func LoadKeyring(dir, activeKID string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("could not list keys: %v", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.pem keys found in %s", dir)
+	}
+
+	keys := make([]*SigningKey, 0, len(paths))
+	for _, path := range paths {
+		key, err := readKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	ring, err := newKeyring(keys, activeKID)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, dir)
+	}
+	return ring, nil
+}
+
+// newKeyring builds a keyring from parsed keys as LoadKeyring describes
+func newKeyring(keys []*SigningKey, activeKID string) (*Keyring, error) {
+	ring := &Keyring{keys: make(map[string]*SigningKey)}
+	var publicOnly []*SigningKey
+	for _, key := range keys {
+		if key.Private == nil {
+			publicOnly = append(publicOnly, key)
+			continue
+		}
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	for _, key := range publicOnly {
+		if ring.hasPublicKey(key.Public) {
+			continue
+		}
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	if activeKID == "" {
+		for id, key := range ring.keys {
+			if key.Private != nil && (activeKID == "" || newerKeyID(id, activeKID)) {
+				activeKID = id
+			}
+		}
+		if activeKID == "" {
+			return nil, fmt.Errorf("no private key found")
+		}
+	}
+	active, ok := ring.keys[activeKID]
+	if !ok || active.Private == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeKID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+// ActiveKeyID returns the kid new tokens are signed with
+func (r *Keyring) ActiveKeyID() string {
+	return r.active.ID
+}
+
+// PublicKey returns the verification key with the given kid
+func (r *Keyring) PublicKey(kid string) (*ecdsa.PublicKey, bool) {
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, false
+	}
+	return key.Public, true
+}
+
+// JWKS returns the public keys in a stable order for publishing
+func (r *Keyring) JWKS() JWKS {
+	ids := make([]string, 0, len(r.keys))
+	for id := range r.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		pub := r.keys[id].Public
+		x, y := make([]byte, 32), make([]byte, 32)
+		pub.X.FillBytes(x)
+		pub.Y.FillBytes(y)
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "EC",
+			Curve:     "P-256",
+			X:         base64.RawURLEncoding.EncodeToString(x),
+			Y:         base64.RawURLEncoding.EncodeToString(y),
+			KeyID:     id,
+			Use:       "sig",
+			Algorithm: "ES256",
+		})
+	}
+	return set
+}
+
+// newerKeyID reports whether kid a should be preferred over b. Generated
+// kids sort by their timestamp and beat hand-named ones like "private".
+func newerKeyID(a, b string) bool {
+	aGenerated, bGenerated := isGeneratedKeyID(a), isGeneratedKeyID(b)
+	if aGenerated != bGenerated {
+		return aGenerated
+	}
+	return a > b
+}
+
+func isGeneratedKeyID(kid string) bool {
+	if len(kid) < len(keyIDTimeFormat) {
+		return false
+	}
+	_, err := time.Parse(keyIDTimeFormat, kid[:len(keyIDTimeFormat)])
+	return err == nil
+}
+
+func (r *Keyring) hasPublicKey(pub *ecdsa.PublicKey) bool {
+	for _, key := range r.keys {
+		if key.Public.Equal(pub) {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateKeyPair creates a new P-256 key in dir named after a timestamp kid
+// and returns the kid
+func GenerateKeyPair(dir string) (string, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode key: %v", err)
+	}
+	suffix, err := GenerateOpaqueToken(3)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("could not create key directory: %v", err)
+	}
+	kid := time.Now().UTC().Format(keyIDTimeFormat) + "-" + suffix
+	path := filepath.Join(dir, kid+".pem")
+	// O_EXCL so an existing key is never overwritten
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("could not create key file: %v", err)
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}); err != nil {
+		return "", fmt.Errorf("could not write key file: %v", err)
+	}
+	return kid, nil
+}
+
+// readKey parses a PEM file holding either a P-256 private or public key.
+// The kid is the file name without the .pem extension.
+func readKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key %s: %v", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse PEM block in %s", path)
+	}
+
+	key := &SigningKey{ID: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key.Private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var parsed any
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if key.Private, ok = parsed.(*ecdsa.PrivateKey); !ok {
+				err = fmt.Errorf("not an ECDSA key")
+			}
+		}
+	case "PUBLIC KEY":
+		var parsed any
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if key.Public, ok = parsed.(*ecdsa.PublicKey); !ok {
+				err = fmt.Errorf("not an ECDSA key")
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %v", path, err)
+	}
+
+	if key.Private != nil {
+		key.Public = &key.Private.PublicKey
+	}
+	if key.Public.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("key %s is not on the P-256 curve", path)
+	}
+	return key, nil
+}
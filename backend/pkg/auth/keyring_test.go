@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func generateKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// writePrivateKey stores key in dir as <kid>.pem in SEC 1 form
+func writePrivateKey(t *testing.T, dir, kid string, key *ecdsa.PrivateKey) {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, kid, "EC PRIVATE KEY", der)
+}
+
+// writePublicKey stores the public half of key in dir as <kid>.pem
+func writePublicKey(t *testing.T, dir, kid string, key *ecdsa.PrivateKey) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, kid, "PUBLIC KEY", der)
+}
+
+func writePEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewerKeyID(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"20250102T000000-abcd", "20250101T000000-abcd", true},
+		{"20250101T000000-abcd", "20250102T000000-abcd", false},
+		{"20250101T000000-abcd", "private", true},
+		{"private", "20250101T000000-abcd", false},
+		{"zeta", "alpha", true},
+		{"alpha", "zeta", false},
+		{"2025", "private", false}, // Too short to carry a timestamp
+	}
+	for _, tt := range tests {
+		if got := newerKeyID(tt.a, tt.b); got != tt.want {
+			t.Errorf("newerKeyID(%q, %q) = %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLoadKeyringActiveKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		private    []string
+		public     []string
+		activeKID  string
+		wantActive string
+		wantErr    string
+	}{
+		{"single hand-named key", []string{"private"}, nil, "", "private", ""},
+		{"generated beats hand-named", []string{"private", "20250101T000000-abcd"}, nil, "", "20250101T000000-abcd", ""},
+		{"newest generated", []string{"20250101T000000-abcd", "20250301T000000-abcd", "20250201T000000-abcd"}, nil, "", "20250301T000000-abcd", ""},
+		{"last hand-named", []string{"alpha", "beta"}, nil, "", "beta", ""},
+		{"public keys never sign", []string{"20250101T000000-abcd"}, []string{"20250301T000000-abcd"}, "", "20250101T000000-abcd", ""},
+		{"explicit", []string{"20250101T000000-abcd", "20250301T000000-abcd"}, nil, "20250101T000000-abcd", "20250101T000000-abcd", ""},
+		{"explicit public key", []string{"private"}, []string{"old"}, "old", "", `active key "old" has no private key`},
+		{"explicit unknown", []string{"private"}, nil, "missing", "", `active key "missing" has no private key`},
+		{"only public keys", nil, []string{"old"}, "", "", "no private key found"},
+		{"empty directory", nil, nil, "", "", "no *.pem keys found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, kid := range tt.private {
+				writePrivateKey(t, dir, kid, generateKey(t, elliptic.P256()))
+			}
+			for _, kid := range tt.public {
+				writePublicKey(t, dir, kid, generateKey(t, elliptic.P256()))
+			}
+
+			ring, err := LoadKeyring(dir, tt.activeKID)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKeyring: %v", err)
+			}
+			if got := ring.ActiveKeyID(); got != tt.wantActive {
+				t.Errorf("active key = %q, want %q", got, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestLoadKeyringCollapsesKeyPair(t *testing.T) {
+	dir := t.TempDir()
+	key := generateKey(t, elliptic.P256())
+	writePrivateKey(t, dir, "private", key)
+	writePublicKey(t, dir, "public", key)
+
+	ring, err := LoadKeyring(dir, "")
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+	keys := ring.JWKS().Keys
+	if len(keys) != 1 || keys[0].KeyID != "private" {
+		t.Errorf("JWKS = %+v, want only the private key", keys)
+	}
+	if _, ok := ring.PublicKey("public"); ok {
+		t.Error("the public half of a loaded private key got its own kid")
+	}
+}
+
+func TestNewKeyringRejectsDuplicateKeyIDs(t *testing.T) {
+	a, b := generateKey(t, elliptic.P256()), generateKey(t, elliptic.P256())
+	tests := []struct {
+		name string
+		keys []*SigningKey
+	}{
+		{"two private keys", []*SigningKey{
+			{ID: "key", Private: a, Public: &a.PublicKey},
+			{ID: "key", Private: b, Public: &b.PublicKey},
+		}},
+		{"private and other public key", []*SigningKey{
+			{ID: "key", Private: a, Public: &a.PublicKey},
+			{ID: "key", Public: &b.PublicKey},
+		}},
+		{"two public keys", []*SigningKey{
+			{ID: "signer", Private: generateKey(t, elliptic.P256())},
+			{ID: "key", Public: &a.PublicKey},
+			{ID: "key", Public: &b.PublicKey},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range tt.keys {
+				if key.Private != nil {
+					key.Public = &key.Private.PublicKey
+				}
+			}
+			_, err := newKeyring(tt.keys, "")
+			if err == nil || !strings.Contains(err.Error(), `duplicate key id "key"`) {
+				t.Errorf("err = %v, want a duplicate key id error", err)
+			}
+		})
+	}
+}
+
+func TestLoadKeyringRejectsOtherCurves(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(t *testing.T, dir, kid string, key *ecdsa.PrivateKey)
+		curve elliptic.Curve
+	}{
+		{"P-384 private key", writePrivateKey, elliptic.P384()},
+		{"P-521 private key", writePrivateKey, elliptic.P521()},
+		{"P-384 public key", writePublicKey, elliptic.P384()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writePrivateKey(t, dir, "private", generateKey(t, elliptic.P256()))
+			tt.write(t, dir, "other", generateKey(t, tt.curve))
+
+			_, err := LoadKeyring(dir, "")
+			if err == nil || !strings.Contains(err.Error(), "not on the P-256 curve") {
+				t.Errorf("err = %v, want a P-256 error", err)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	signer, old := generateKey(t, elliptic.P256()), generateKey(t, elliptic.P256())
+	writePrivateKey(t, dir, "20250301T000000-abcd", signer)
+	writePublicKey(t, dir, "20250101T000000-abcd", old)
+
+	ring, err := LoadKeyring(dir, "")
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+	keys := ring.JWKS().Keys
+	want := []struct {
+		kid string
+		key *ecdsa.PublicKey
+	}{
+		{"20250101T000000-abcd", &old.PublicKey},
+		{"20250301T000000-abcd", &signer.PublicKey},
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("JWKS has %d keys, want %d", len(keys), len(want))
+	}
+	for i, w := range want {
+		jwk := keys[i]
+		if jwk.KeyID != w.kid || jwk.KeyType != "EC" || jwk.Curve != "P-256" || jwk.Use != "sig" || jwk.Algorithm != "ES256" {
+			t.Errorf("key %d = %+v, want %s as an ES256 signing key", i, jwk, w.kid)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			t.Fatalf("key %d coordinates %q, %q are not 32 byte base64url", i, jwk.X, jwk.Y)
+		}
+		if new(big.Int).SetBytes(x).Cmp(w.key.X) != 0 || new(big.Int).SetBytes(y).Cmp(w.key.Y) != 0 {
+			t.Errorf("key %d coordinates don't match %s", i, w.kid)
+		}
+	}
+}
+
+func TestRotatedOutKeyStillVerifies(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	old := generateKey(t, elliptic.P256())
+	writePrivateKey(t, oldDir, "20250101T000000-abcd", old)
+	oldRing, err := LoadKeyring(oldDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := NewJWTManager(oldRing, time.Hour).GenerateToken(uuid.New(), "user", uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After rotation only the public half of the old key is kept
+	writePrivateKey(t, newDir, "20250301T000000-abcd", generateKey(t, elliptic.P256()))
+	writePublicKey(t, newDir, "20250101T000000-abcd", old)
+	newRing, err := LoadKeyring(newDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newRing.ActiveKeyID() != "20250301T000000-abcd" {
+		t.Fatalf("active key = %s, want the new key", newRing.ActiveKeyID())
+	}
+	if _, err := NewJWTManager(newRing, time.Hour).ValidateToken(token); err != nil {
+		t.Errorf("token signed with the rotated-out key: %v", err)
+	}
+
+	// Once the old key is dropped its tokens stop verifying
+	os.Remove(filepath.Join(newDir, "20250101T000000-abcd.pem"))
+	droppedRing, err := LoadKeyring(newDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewJWTManager(droppedRing, time.Hour).ValidateToken(token); err == nil {
+		t.Error("token signed with a dropped key was accepted")
+	}
+}
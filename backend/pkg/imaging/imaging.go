@@ -0,0 +1,167 @@
+// Package imaging validates uploaded images and re-encodes them at a fixed
+// size. Re-encoding from decoded pixels drops EXIF and any other metadata
+// the upload carried.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type, use JPEG, PNG or GIF")
+	ErrTooLarge        = errors.New("image file is too large")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+	ErrInvalidImage    = errors.New("image could not be decoded")
+)
+
+// jpegQuality is used when re-encoding JPEG uploads
+const jpegQuality = 85
+
+// Options bound the accepted input and set the output size
+type Options struct {
+	MaxBytes  int // Largest accepted upload
+	MaxPixels int // Largest accepted width × height, checked before decoding
+	Width     int // Output width
+	Height    int // Output height
+}
+
+// Result is a processed image ready to store
+type Result struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+}
+
+// Process sniffs the type of data, checks it against opts, then crops it
+// to the output aspect ratio, scales it to the output size and re-encodes
+// it. JPEGs stay JPEG; PNGs and GIFs become PNG, keeping transparency and
+// only the first GIF frame.
+func Process(data []byte, opts Options) (*Result, error) {
+	if len(data) > opts.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	// Trust the bytes, not the client supplied Content-Type or file name
+	contentType := http.DetectContentType(data)
+	var decode func([]byte) (image.Image, error)
+	switch contentType {
+	case "image/jpeg":
+		decode = decodeJPEG
+	case "image/png":
+		decode = func(data []byte) (image.Image, error) { return png.Decode(bytes.NewReader(data)) }
+	case "image/gif":
+		decode = func(data []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(data)) }
+	default:
+		return nil, ErrUnsupportedType
+	}
+
+	// Read the header first so a decompression bomb is refused before its
+	// pixels are allocated
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if config.Width > opts.MaxPixels/config.Height {
+		return nil, ErrTooManyPixels
+	}
+
+	img, err := decode(data)
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	img = resizeToFill(img, opts.Width, opts.Height)
+
+	var out bytes.Buffer
+	result := &Result{}
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality})
+		result.ContentType, result.Extension = "image/jpeg", ".jpg"
+	} else {
+		err = png.Encode(&out, img)
+		result.ContentType, result.Extension = "image/png", ".png"
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %v", err)
+	}
+	result.Data = out.Bytes()
+	return result, nil
+}
+
+// decodeJPEG decodes a JPEG and applies its EXIF orientation, since the
+// tag itself is dropped on re-encode
+func decodeJPEG(data []byte) (image.Image, error) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return applyOrientation(img, jpegOrientation(data)), nil
+}
+
+// resizeToFill scales img to cover width × height and crops the overflow
+// around the center
+func resizeToFill(img image.Image, width, height int) *image.RGBA {
+	src := img.Bounds()
+	srcW, srcH := src.Dx(), src.Dy()
+
+	// Largest centered crop with the target aspect ratio
+	cropW, cropH := srcW, srcW*height/width
+	if cropH > srcH {
+		cropW, cropH = srcH*width/height, srcH
+	}
+	if cropW < 1 {
+		cropW = 1
+	}
+	if cropH < 1 {
+		cropH = 1
+	}
+	crop := image.Rect(0, 0, cropW, cropH).Add(src.Min).
+		Add(image.Pt((srcW-cropW)/2, (srcH-cropH)/2))
+
+	rgba := image.NewRGBA(image.Rect(0, 0, cropW, cropH))
+	draw.Draw(rgba, rgba.Bounds(), img, crop.Min, draw.Src)
+	return scale(rgba, width, height)
+}
+
+// scale resamples src to width × height. Each output pixel averages the
+// source pixels it covers, which avoids aliasing when shrinking; when
+// enlarging it falls back to the nearest source pixel.
+func scale(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := max((y+1)*srcH/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := max((x+1)*srcW/width, x0+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
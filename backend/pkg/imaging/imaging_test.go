@@ -0,0 +1,271 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	green = color.RGBA{0, 255, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+)
+
+var testOptions = Options{MaxBytes: 1 << 20, MaxPixels: 1 << 20, Width: 32, Height: 32}
+
+// stripes returns a width × height image split into vertical stripes of
+// the given colors
+func stripes(width, height int, colors ...color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, colors[x*len(colors)/width])
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF APP1 segment carrying the orientation
+// tag right after the JPEG's start of image marker
+func withOrientation(jpg []byte, orientation uint16, order binary.ByteOrder) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8) // First IFD offset
+	order.PutUint16(tiff[8:], 1) // One entry
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1) // One value
+	order.PutUint16(tiff[18:], orientation)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, app1...)
+	return append(out, jpg[2:]...)
+}
+
+// near reports whether c is within a JPEG's tolerance of want
+func near(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	diff := func(got uint32, want uint8) bool {
+		d := int(got>>8) - int(want)
+		return d > -48 && d < 48
+	}
+	return diff(r, want.R) && diff(g, want.G) && diff(b, want.B)
+}
+
+func TestProcessSniffsType(t *testing.T) {
+	img := stripes(64, 64, red)
+	tests := []struct {
+		name            string
+		data            []byte
+		wantContentType string
+		wantExtension   string
+		wantErr         error
+	}{
+		{"jpeg stays jpeg", encodeJPEG(t, img), "image/jpeg", ".jpg", nil},
+		{"png stays png", encodePNG(t, img), "image/png", ".png", nil},
+		{"gif becomes png", encodeGIF(t, img), "image/png", ".png", nil},
+		{"text", []byte("hello, this is not an image"), "", "", ErrUnsupportedType},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "", "", ErrUnsupportedType},
+		{"truncated png", encodePNG(t, img)[:20], "", "", ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Process(tt.data, testOptions)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if result.ContentType != tt.wantContentType || result.Extension != tt.wantExtension {
+				t.Errorf("got %s %s, want %s %s", result.ContentType, result.Extension, tt.wantContentType, tt.wantExtension)
+			}
+		})
+	}
+}
+
+func TestProcessLimits(t *testing.T) {
+	data := encodePNG(t, stripes(100, 50, red))
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr error
+	}{
+		{"within limits", Options{MaxBytes: len(data), MaxPixels: 5000, Width: 10, Height: 10}, nil},
+		{"too many bytes", Options{MaxBytes: len(data) - 1, MaxPixels: 5000, Width: 10, Height: 10}, ErrTooLarge},
+		{"too many pixels", Options{MaxBytes: len(data), MaxPixels: 4999, Width: 10, Height: 10}, ErrTooManyPixels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(data, tt.opts); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessOutputSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		outW, outH    int
+	}{
+		{"shrink square", 300, 300, 64, 64},
+		{"crop wide to square", 300, 100, 64, 64},
+		{"crop tall to banner", 100, 300, 150, 50},
+		{"enlarge", 10, 10, 40, 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := encodePNG(t, stripes(tt.width, tt.height, red, green, blue))
+			result, err := Process(data, Options{MaxBytes: 1 << 20, MaxPixels: 1 << 20, Width: tt.outW, Height: tt.outH})
+			if err != nil {
+				t.Fatalf("Process: %v", err)
+			}
+			out, err := png.Decode(bytes.NewReader(result.Data))
+			if err != nil {
+				t.Fatalf("output is not a PNG: %v", err)
+			}
+			if b := out.Bounds(); b.Dx() != tt.outW || b.Dy() != tt.outH {
+				t.Errorf("output is %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.outW, tt.outH)
+			}
+			// The crop is centered, so the middle stripe stays in the middle
+			if c := out.At(tt.outW/2, tt.outH/2); !near(c, green) {
+				t.Errorf("center pixel = %v, want green", c)
+			}
+		})
+	}
+}
+
+func TestProcessCropsWideImagesAroundCenter(t *testing.T) {
+	// A 300x100 image cropped to a square keeps only its green middle third
+	data := encodePNG(t, stripes(300, 100, red, green, blue))
+	result, err := Process(data, testOptions)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	out, _ := png.Decode(bytes.NewReader(result.Data))
+	for _, p := range []image.Point{{0, 0}, {31, 0}, {0, 31}, {31, 31}} {
+		if c := out.At(p.X, p.Y); !near(c, green) {
+			t.Errorf("pixel %v = %v, want green", p, c)
+		}
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	jpg := encodeJPEG(t, stripes(16, 16, red))
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", jpg, 1},
+		{"big endian 6", withOrientation(jpg, 6, binary.BigEndian), 6},
+		{"little endian 8", withOrientation(jpg, 8, binary.LittleEndian), 8},
+		{"mirrored 2", withOrientation(jpg, 2, binary.BigEndian), 2},
+		{"out of range", withOrientation(jpg, 9, binary.BigEndian), 1},
+		{"not a jpeg", []byte("GIF89a"), 1},
+		{"truncated segment", withOrientation(jpg, 6, binary.BigEndian)[:10], 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessAppliesOrientation(t *testing.T) {
+	// A wide image, red on the left and blue on the right, tagged as
+	// needing a rotation. Output keeps the upright aspect ratio so nothing
+	// is cropped away.
+	wide := encodeJPEG(t, stripes(64, 32, red, blue))
+	tests := []struct {
+		name        string
+		orientation uint16
+		outW, outH  int
+		// Colors expected at the first and last quarter of the upright
+		// image, along its longer side
+		first, last color.RGBA
+	}{
+		{"upright", 1, 64, 32, red, blue},
+		{"mirrored", 2, 64, 32, blue, red},
+		{"rotated 180", 3, 64, 32, blue, red},
+		{"rotated 90 clockwise", 6, 32, 64, red, blue},
+		{"rotated 90 counter-clockwise", 8, 32, 64, blue, red},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := withOrientation(wide, tt.orientation, binary.BigEndian)
+			result, err := Process(data, Options{MaxBytes: 1 << 20, MaxPixels: 1 << 20, Width: tt.outW, Height: tt.outH})
+			if err != nil {
+				t.Fatalf("Process: %v", err)
+			}
+			out, err := jpeg.Decode(bytes.NewReader(result.Data))
+			if err != nil {
+				t.Fatalf("output is not a JPEG: %v", err)
+			}
+			if bytes.Contains(result.Data, []byte("Exif")) {
+				t.Error("output still carries EXIF data")
+			}
+
+			var first, last image.Point
+			if tt.outW > tt.outH {
+				first, last = image.Pt(tt.outW/4, tt.outH/2), image.Pt(tt.outW*3/4, tt.outH/2)
+			} else {
+				first, last = image.Pt(tt.outW/2, tt.outH/4), image.Pt(tt.outW/2, tt.outH*3/4)
+			}
+			if c := out.At(first.X, first.Y); !near(c, tt.first) {
+				t.Errorf("pixel %v = %v, want %v", first, c, tt.first)
+			}
+			if c := out.At(last.X, last.Y); !near(c, tt.last) {
+				t.Errorf("pixel %v = %v, want %v", last, c, tt.last)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Package mergepatch applies JSON merge patches as defined by RFC 7396
+package mergepatch
+
+import (
+	"encoding/json"
+)
+
+// Apply merges patch into doc and returns the result. Objects in the patch
+// are merged key by key, null removes a key, and any other value replaces
+// the target outright. doc may be empty, which is treated as null.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var target any
+	if len(doc) > 0 {
+		if err := json.Unmarshal(doc, &target); err != nil {
+			return nil, err
+		}
+	}
+	var p any
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = merge(targetObject[key], value)
+	}
+	return targetObject
+}
@@ -2,42 +2,225 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/dfanso/reddit-clone/internal/models"
 	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/dfanso/reddit-clone/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
-// AuthMiddleware verifies the JWT token and extracts user data into context
-func AuthMiddleware(jwtManager *auth.JWTManager) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				http.Error(w, "Authorization header missing", http.StatusUnauthorized)
-				return
+// Typed context keys so values set by the auth middleware can't collide
+// with string keys set elsewhere
+type claimsContextKey struct{}
+type userContextKey struct{}
+type apiTokenContextKey struct{}
+
+// UserLookup loads the user a token was issued for
+type UserLookup interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// RevocationChecker reports whether a token was revoked before it expired
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *auth.JWTClaims) (bool, error)
+}
+
+// SessionTracker confirms a token's session is still active and records
+// its use
+type SessionTracker interface {
+	Exists(ctx context.Context, id uuid.UUID) (bool, error)
+	Touch(id uuid.UUID)
+}
+
+// APITokenLookup resolves a personal API token to its active record
+type APITokenLookup interface {
+	Authenticate(ctx context.Context, plaintext string) (*models.APIToken, error)
+}
+
+// Authenticator verifies bearer tokens on protected routes
+type Authenticator struct {
+	jwtManager  *auth.JWTManager
+	users       UserLookup
+	revocations RevocationChecker
+	sessions    SessionTracker
+	apiTokens   APITokenLookup
+}
+
+func NewAuthenticator(jwtManager *auth.JWTManager, users UserLookup, revocations RevocationChecker, sessions SessionTracker, apiTokens APITokenLookup) *Authenticator {
+	return &Authenticator{
+		jwtManager:  jwtManager,
+		users:       users,
+		revocations: revocations,
+		sessions:    sessions,
+		apiTokens:   apiTokens,
+	}
+}
+
+// Middleware verifies the JWT, rejects revoked tokens and tokens of ended
+// sessions, confirms the user still exists, is not banned or suspended and
+// has completed signup, then stores the claims and user on the request
+// context.
+// Personal API tokens are only accepted when the route lists scopes, and
+// must have been granted all of them. Routes without scopes stay limited to
+// signed-in sessions, so a token can never manage credentials.
+func (a *Authenticator) Middleware(scopes ...models.APIScope) echo.MiddlewareFunc {
+	return a.authenticate(false, scopes)
+}
+
+// SignupMiddleware is Middleware for the endpoints that finish signup, so
+// it also lets in users who have not completed it yet
+func (a *Authenticator) SignupMiddleware() echo.MiddlewareFunc {
+	return a.authenticate(true, nil)
+}
+
+func (a *Authenticator) authenticate(allowIncompleteSignup bool, scopes []models.APIScope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			tokenString, err := bearerToken(c.Request())
+			if err != nil {
+				return utils.ErrorResponse(c, http.StatusUnauthorized, err.Error(), nil)
 			}
 
-			// Extract token from "Bearer <token>"
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
-				return
+			if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+				return a.authenticateAPIToken(c, next, tokenString, scopes)
 			}
 
-			tokenString := parts[1]
-			claims, err := jwtManager.ValidateToken(tokenString)
+			claims, err := a.jwtManager.ValidateToken(tokenString)
 			if err != nil {
-				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
-				return
+				return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token", nil)
+			}
+
+			revoked, err := a.revocations.IsRevoked(c.Request().Context(), claims)
+			if err != nil {
+				return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to check token revocation", err)
+			}
+			if revoked {
+				return utils.ErrorResponse(c, http.StatusUnauthorized, "Token has been revoked", nil)
 			}
 
-			//TODO: Check if user is exists in database
+			active, err := a.sessions.Exists(c.Request().Context(), claims.SessionID)
+			if err != nil {
+				return utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to check session", err)
+			}
+			if !active {
+				return utils.ErrorResponse(c, http.StatusUnauthorized, "Session has ended", nil)
+			}
+
+			// Check the user still exists and is allowed in
+			user, failure := a.loadUser(c.Request().Context(), claims.UserID, allowIncompleteSignup)
+			if failure != nil {
+				return failure.respond(c)
+			}
+
+			// Store claims and user in request context
+			ctx := context.WithValue(c.Request().Context(), claimsContextKey{}, claims)
+			ctx = context.WithValue(ctx, userContextKey{}, user)
+			c.SetRequest(c.Request().WithContext(ctx))
+			a.sessions.Touch(claims.SessionID)
+
+			return next(c)
+		}
+	}
+}
+
+// authenticateAPIToken checks a personal API token against the route's
+// scopes, then stores the token and its user on the request context
+func (a *Authenticator) authenticateAPIToken(c echo.Context, next echo.HandlerFunc, tokenString string, scopes []models.APIScope) error {
+	if len(scopes) == 0 {
+		return utils.ErrorResponse(c, http.StatusForbidden, "API tokens are not accepted on this route", nil)
+	}
+
+	token, err := a.apiTokens.Authenticate(c.Request().Context(), tokenString)
+	if err != nil {
+		return utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid or expired token", nil)
+	}
+	for _, scope := range scopes {
+		if !token.HasScope(scope) {
+			return utils.ErrorResponse(c, http.StatusForbidden, "Token is missing a required scope", fmt.Errorf("scope %q required", scope))
+		}
+	}
+
+	user, failure := a.loadUser(c.Request().Context(), token.UserID, false)
+	if failure != nil {
+		return failure.respond(c)
+	}
+
+	ctx := context.WithValue(c.Request().Context(), apiTokenContextKey{}, token)
+	ctx = context.WithValue(ctx, userContextKey{}, user)
+	c.SetRequest(c.Request().WithContext(ctx))
+
+	return next(c)
+}
+
+// authFailure describes why a request was turned away
+type authFailure struct {
+	status  int
+	message string
+	err     error
+}
+
+func (f *authFailure) respond(c echo.Context) error {
+	return utils.ErrorResponse(c, f.status, f.message, f.err)
+}
+
+// loadUser checks the user still exists and is allowed in
+func (a *Authenticator) loadUser(ctx context.Context, userID uuid.UUID, allowIncompleteSignup bool) (*models.User, *authFailure) {
+	user, err := a.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &authFailure{http.StatusUnauthorized, "User no longer exists", nil}
+		}
+		return nil, &authFailure{http.StatusInternalServerError, "Failed to load user", err}
+	}
+	if user.Status == models.StatusBanned {
+		return nil, &authFailure{http.StatusForbidden, "User is banned", nil}
+	}
+	if user.IsSuspended(time.Now()) {
+		until := user.SuspendedUntil.UTC().Format(time.RFC3339)
+		return nil, &authFailure{http.StatusForbidden, "Account is suspended", fmt.Errorf("suspended until %s", until)}
+	}
+	if !allowIncompleteSignup && user.Stage != models.StageCompleted {
+		return nil, &authFailure{http.StatusForbidden, "Signup is not complete", fmt.Errorf("current signup stage is %q", user.Stage)}
+	}
+	return user, nil
+}
 
-			// Store user_id and role in request context
-			ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
-			ctx = context.WithValue(ctx, "role", claims.Role)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", errors.New("Authorization header missing")
 	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+		return "", errors.New("Invalid Authorization header format")
+	}
+	return parts[1], nil
+}
+
+// ClaimsFromContext returns the JWT claims stored by the auth middleware
+func ClaimsFromContext(c echo.Context) (*auth.JWTClaims, bool) {
+	claims, ok := c.Request().Context().Value(claimsContextKey{}).(*auth.JWTClaims)
+	return claims, ok
+}
+
+// APITokenFromContext returns the personal API token the request was
+// authenticated with, if any
+func APITokenFromContext(c echo.Context) (*models.APIToken, bool) {
+	token, ok := c.Request().Context().Value(apiTokenContextKey{}).(*models.APIToken)
+	return token, ok
+}
+
+// UserFromContext returns the authenticated user stored by the auth middleware
+func UserFromContext(c echo.Context) (*models.User, bool) {
+	user, ok := c.Request().Context().Value(userContextKey{}).(*models.User)
+	return user, ok
 }
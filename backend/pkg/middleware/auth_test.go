@@ -0,0 +1,401 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dfanso/reddit-clone/internal/models"
+	"github.com/dfanso/reddit-clone/pkg/auth"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type fakeUsers map[uuid.UUID]*models.User
+
+func (f fakeUsers) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, ok := f[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// fakeRevocations holds revoked jtis
+type fakeRevocations map[string]bool
+
+func (f fakeRevocations) IsRevoked(ctx context.Context, claims *auth.JWTClaims) (bool, error) {
+	return f[claims.ID], nil
+}
+
+type fakeSessions struct {
+	active  map[uuid.UUID]bool
+	touched []uuid.UUID
+}
+
+func (f *fakeSessions) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	return f.active[id], nil
+}
+
+func (f *fakeSessions) Touch(id uuid.UUID) {
+	f.touched = append(f.touched, id)
+}
+
+// fakeAPITokens maps plaintext tokens to their records
+type fakeAPITokens struct {
+	tokens map[string]*models.APIToken
+	calls  int
+}
+
+func (f *fakeAPITokens) Authenticate(ctx context.Context, plaintext string) (*models.APIToken, error) {
+	f.calls++
+	token, ok := f.tokens[plaintext]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return token, nil
+}
+
+// authFixture is an Authenticator over in-memory fakes
+type authFixture struct {
+	authenticator *Authenticator
+	jwtManager    *auth.JWTManager
+	users         fakeUsers
+	revocations   fakeRevocations
+	sessions      *fakeSessions
+	apiTokens     *fakeAPITokens
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+	dir := t.TempDir()
+	if _, err := auth.GenerateKeyPair(dir); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := auth.LoadKeyring(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &authFixture{
+		jwtManager:  auth.NewJWTManager(keyring, time.Hour),
+		users:       fakeUsers{},
+		revocations: fakeRevocations{},
+		sessions:    &fakeSessions{active: map[uuid.UUID]bool{}},
+		apiTokens:   &fakeAPITokens{tokens: map[string]*models.APIToken{}},
+	}
+	f.authenticator = NewAuthenticator(f.jwtManager, f.users, f.revocations, f.sessions, f.apiTokens)
+	return f
+}
+
+// addUser stores a signed-up user, changed by edit, and returns it
+func (f *authFixture) addUser(edit func(*models.User)) *models.User {
+	user := &models.User{ID: uuid.New(), Status: models.StatusVerified, Stage: models.StageCompleted}
+	if edit != nil {
+		edit(user)
+	}
+	f.users[user.ID] = user
+	return user
+}
+
+// signIn issues an access token for user on a new active session
+func (f *authFixture) signIn(t *testing.T, user *models.User) (string, *auth.JWTClaims) {
+	t.Helper()
+	sessionID := uuid.New()
+	f.sessions.active[sessionID] = true
+	token, err := f.jwtManager.GenerateToken(user.ID, string(models.RoleUser), sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := f.jwtManager.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, claims
+}
+
+// serve runs a request with the Authorization header through mw and
+// reports the status, error message and whether the handler ran
+func serve(t *testing.T, mw echo.MiddlewareFunc, authorization string) (int, string, bool) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	reached := false
+	err := mw(func(c echo.Context) error {
+		reached = true
+		if _, ok := UserFromContext(c); !ok {
+			t.Error("handler ran without a user on the context")
+		}
+		return c.NoContent(http.StatusOK)
+	})(c)
+	if err != nil {
+		t.Fatalf("middleware returned %v", err)
+	}
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("response body %q: %v", rec.Body, err)
+		}
+	}
+	return rec.Code, body.Message, reached
+}
+
+func TestMiddlewareRejections(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(t *testing.T, f *authFixture) string // Returns the Authorization header
+		signup      bool                                      // Use SignupMiddleware
+		wantStatus  int
+		wantMessage string
+	}{
+		{
+			name: "signed-in user",
+			setup: func(t *testing.T, f *authFixture) string {
+				token, _ := f.signIn(t, f.addUser(nil))
+				return "Bearer " + token
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "missing header",
+			setup:       func(t *testing.T, f *authFixture) string { return "" },
+			wantStatus:  http.StatusUnauthorized,
+			wantMessage: "Authorization header missing",
+		},
+		{
+			name:        "not a bearer token",
+			setup:       func(t *testing.T, f *authFixture) string { return "Basic dXNlcjpwYXNz" },
+			wantStatus:  http.StatusUnauthorized,
+			wantMessage: "Invalid Authorization header format",
+		},
+		{
+			name:        "invalid token",
+			setup:       func(t *testing.T, f *authFixture) string { return "Bearer not-a-jwt" },
+			wantStatus:  http.StatusUnauthorized,
+			wantMessage: "Invalid or expired token",
+		},
+		{
+			name: "revoked jti",
+			setup: func(t *testing.T, f *authFixture) string {
+				token, claims := f.signIn(t, f.addUser(nil))
+				f.revocations[claims.ID] = true
+				return "Bearer " + token
+			},
+			wantStatus:  http.StatusUnauthorized,
+			wantMessage: "Token has been revoked",
+		},
+		{
+			name: "deleted session",
+			setup: func(t *testing.T, f *authFixture) string {
+				token, claims := f.signIn(t, f.addUser(nil))
+				delete(f.sessions.active, claims.SessionID)
+				return "Bearer " + token
+			},
+			wantStatus:  http.StatusUnauthorized,
+			wantMessage: "Session has ended",
+		},
+		{
+			name: "deleted user",
+			setup: func(t *testing.T, f *authFixture) string {
+				user := f.addUser(nil)
+				token, _ := f.signIn(t, user)
+				delete(f.users, user.ID)
+				return "Bearer " + token
+			},
+			wantStatus:  http.StatusUnauthorized,
+			wantMessage: "User no longer exists",
+		},
+		{
+			name: "banned user",
+			setup: func(t *testing.T, f *authFixture) string {
+				token, _ := f.signIn(t, f.addUser(func(u *models.User) { u.Status = models.StatusBanned }))
+				return "Bearer " + token
+			},
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "User is banned",
+		},
+		{
+			name: "banned user finishing signup",
+			setup: func(t *testing.T, f *authFixture) string {
+				token, _ := f.signIn(t, f.addUser(func(u *models.User) {
+					u.Status = models.StatusBanned
+					u.Stage = models.StageEmailVerified
+				}))
+				return "Bearer " + token
+			},
+			signup:      true,
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "User is banned",
+		},
+		{
+			name: "suspended user",
+			setup: func(t *testing.T, f *authFixture) string {
+				until := time.Now().Add(time.Hour)
+				token, _ := f.signIn(t, f.addUser(func(u *models.User) { u.SuspendedUntil = &until }))
+				return "Bearer " + token
+			},
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "Account is suspended",
+		},
+		{
+			name: "suspension over",
+			setup: func(t *testing.T, f *authFixture) string {
+				until := time.Now().Add(-time.Minute)
+				token, _ := f.signIn(t, f.addUser(func(u *models.User) { u.SuspendedUntil = &until }))
+				return "Bearer " + token
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "incomplete signup",
+			setup: func(t *testing.T, f *authFixture) string {
+				token, _ := f.signIn(t, f.addUser(func(u *models.User) { u.Stage = models.StageEmailVerified }))
+				return "Bearer " + token
+			},
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "Signup is not complete",
+		},
+		{
+			name: "incomplete signup finishing signup",
+			setup: func(t *testing.T, f *authFixture) string {
+				token, _ := f.signIn(t, f.addUser(func(u *models.User) { u.Stage = models.StageGoogleSSO }))
+				return "Bearer " + token
+			},
+			signup:     true,
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAuthFixture(t)
+			authorization := tt.setup(t, f)
+			mw := f.authenticator.Middleware()
+			if tt.signup {
+				mw = f.authenticator.SignupMiddleware()
+			}
+
+			status, message, reached := serve(t, mw, authorization)
+			if status != tt.wantStatus || message != tt.wantMessage {
+				t.Errorf("got %d %q, want %d %q", status, message, tt.wantStatus, tt.wantMessage)
+			}
+			if reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("handler ran = %t", reached)
+			}
+			if reached && len(f.sessions.touched) != 1 {
+				t.Errorf("session touched %d times, want once", len(f.sessions.touched))
+			}
+		})
+	}
+}
+
+func TestMiddlewareAPITokens(t *testing.T) {
+	const plaintext = models.APITokenPrefix + "secret"
+	tests := []struct {
+		name        string
+		scopes      []models.APIScope // Granted to the token
+		user        func(*models.User)
+		signup      bool              // Use SignupMiddleware
+		routeScopes []models.APIScope // Required by the route
+		token       string
+		wantStatus  int
+		wantMessage string
+		wantLookup  bool
+	}{
+		{
+			name:        "all scopes granted",
+			scopes:      []models.APIScope{models.ScopeRead, models.ScopeVote},
+			routeScopes: []models.APIScope{models.ScopeRead, models.ScopeVote},
+			token:       plaintext,
+			wantStatus:  http.StatusOK,
+			wantLookup:  true,
+		},
+		{
+			name:        "route without scopes",
+			scopes:      models.APIScopes,
+			token:       plaintext,
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "API tokens are not accepted on this route",
+		},
+		{
+			name:        "signup route",
+			scopes:      models.APIScopes,
+			signup:      true,
+			token:       plaintext,
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "API tokens are not accepted on this route",
+		},
+		{
+			name:        "unknown token",
+			scopes:      models.APIScopes,
+			routeScopes: []models.APIScope{models.ScopeRead},
+			token:       models.APITokenPrefix + "other",
+			wantStatus:  http.StatusUnauthorized,
+			wantMessage: "Invalid or expired token",
+			wantLookup:  true,
+		},
+		{
+			name:        "missing scope",
+			scopes:      []models.APIScope{models.ScopeRead},
+			routeScopes: []models.APIScope{models.ScopeRead, models.ScopeSubmit},
+			token:       plaintext,
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "Token is missing a required scope",
+			wantLookup:  true,
+		},
+		{
+			name:        "banned owner",
+			scopes:      models.APIScopes,
+			user:        func(u *models.User) { u.Status = models.StatusBanned },
+			routeScopes: []models.APIScope{models.ScopeRead},
+			token:       plaintext,
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "User is banned",
+			wantLookup:  true,
+		},
+		{
+			name:        "owner with incomplete signup",
+			scopes:      models.APIScopes,
+			user:        func(u *models.User) { u.Stage = models.StageEmailVerified },
+			routeScopes: []models.APIScope{models.ScopeRead},
+			token:       plaintext,
+			wantStatus:  http.StatusForbidden,
+			wantMessage: "Signup is not complete",
+			wantLookup:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAuthFixture(t)
+			user := f.addUser(tt.user)
+			f.apiTokens.tokens[plaintext] = &models.APIToken{ID: uuid.New(), UserID: user.ID, Scopes: tt.scopes}
+			mw := f.authenticator.Middleware(tt.routeScopes...)
+			if tt.signup {
+				mw = f.authenticator.SignupMiddleware()
+			}
+
+			status, message, reached := serve(t, mw, "Bearer "+tt.token)
+			if status != tt.wantStatus || message != tt.wantMessage {
+				t.Errorf("got %d %q, want %d %q", status, message, tt.wantStatus, tt.wantMessage)
+			}
+			if reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("handler ran = %t", reached)
+			}
+			if lookedUp := f.apiTokens.calls > 0; lookedUp != tt.wantLookup {
+				t.Errorf("token looked up = %t, want %t", lookedUp, tt.wantLookup)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://accounts.example.com"
+	testClientID = "client-123"
+	testNonce    = "nonce-abc"
+)
+
+// fakeProvider is a minimal OIDC provider serving a JWKS document and a
+// token endpoint that enforces PKCE
+type fakeProvider struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu          sync.Mutex
+	keys        []jsonWebKey
+	jwksFetches int
+	challenges  map[string]string // authorization code -> code challenge
+	idToken     string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	f := &fakeProvider{t: t, challenges: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jwks", f.serveJWKS)
+	mux.HandleFunc("POST /token", f.serveToken)
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeProvider) config() Config {
+	return Config{
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "https://app.example.com/callback",
+		AuthURL:      f.server.URL + "/authorize",
+		TokenURL:     f.server.URL + "/token",
+		JWKSURL:      f.server.URL + "/jwks",
+		Issuer:       testIssuer,
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+func (f *fakeProvider) setKeys(keys ...jsonWebKey) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys = keys
+}
+
+func (f *fakeProvider) fetches() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.jwksFetches
+}
+
+// authorize stands in for the user approving the consent screen: it
+// records the challenge from the authorization URL under a new code
+func (f *fakeProvider) authorize(authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if method := u.Query().Get("code_challenge_method"); method != "S256" {
+		f.t.Fatalf("code_challenge_method = %q, want S256", method)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	code := "code-" + u.Query().Get("state")
+	f.challenges[code] = u.Query().Get("code_challenge")
+	return code
+}
+
+func (f *fakeProvider) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jwksFetches++
+	json.NewEncoder(w).Encode(map[string]any{"keys": f.keys})
+}
+
+func (f *fakeProvider) serveToken(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fail := func(code, description string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+	}
+
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		fail("unsupported_grant_type", "")
+		return
+	}
+	if r.PostFormValue("client_id") != testClientID || r.PostFormValue("client_secret") != "secret" {
+		fail("invalid_client", "")
+		return
+	}
+	challenge, ok := f.challenges[r.PostFormValue("code")]
+	if !ok {
+		fail("invalid_grant", "unknown code")
+		return
+	}
+	delete(f.challenges, r.PostFormValue("code"))
+	if CodeChallengeS256(r.PostFormValue("code_verifier")) != challenge {
+		fail("invalid_grant", "PKCE verification failed")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": f.idToken})
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func validClaims() *IDTokenClaims {
+	now := time.Now()
+	return &IDTokenClaims{
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Nonce:         testNonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Subject:   "google-sub-1",
+			Audience:  jwt.ClaimStrings{testClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestCodeChallengeS256(t *testing.T) {
+	// RFC 7636 Appendix B
+	got := CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("CodeChallengeS256 = %q, want %q", got, want)
+	}
+}
+
+func TestRandomString(t *testing.T) {
+	a, err := RandomString(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := RandomString(32)
+	if len(a) != 43 || a == b {
+		t.Errorf("RandomString(32) = %q, %q, want two distinct 43 character strings", a, b)
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		authURL  string
+		wantBase string
+		extra    map[string]string
+	}{
+		{"plain", "https://accounts.example.com/auth", "https://accounts.example.com/auth", nil},
+		{"existing query", "https://accounts.example.com/auth?prompt=select_account", "https://accounts.example.com/auth", map[string]string{"prompt": "select_account"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProvider(Config{ClientID: testClientID, RedirectURL: "https://app.example.com/callback", AuthURL: tt.authURL, Scopes: []string{"openid", "email"}})
+			u, err := url.Parse(p.AuthCodeURL("state-1", testNonce, "verifier"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if base := u.Scheme + "://" + u.Host + u.Path; base != tt.wantBase {
+				t.Errorf("base = %q, want %q", base, tt.wantBase)
+			}
+			want := map[string]string{
+				"response_type":         "code",
+				"client_id":             testClientID,
+				"redirect_uri":          "https://app.example.com/callback",
+				"scope":                 "openid email",
+				"state":                 "state-1",
+				"nonce":                 testNonce,
+				"code_challenge":        CodeChallengeS256("verifier"),
+				"code_challenge_method": "S256",
+			}
+			for k, v := range tt.extra {
+				want[k] = v
+			}
+			for k, v := range want {
+				if got := u.Query().Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestExchangeSendsCodeVerifier(t *testing.T) {
+	f := newFakeProvider(t)
+	f.idToken = "raw-id-token"
+	p := NewProvider(f.config())
+	ctx := context.Background()
+
+	code := f.authorize(p.AuthCodeURL("s1", testNonce, "right-verifier"))
+	if _, err := p.Exchange(ctx, code, "wrong-verifier"); err == nil || !strings.Contains(err.Error(), "PKCE") {
+		t.Errorf("Exchange with the wrong verifier: err = %v, want a PKCE failure", err)
+	}
+
+	code = f.authorize(p.AuthCodeURL("s2", testNonce, "right-verifier"))
+	got, err := p.Exchange(ctx, code, "right-verifier")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if got != "raw-id-token" {
+		t.Errorf("Exchange = %q, want raw-id-token", got)
+	}
+
+	// Codes are single use
+	if _, err := p.Exchange(ctx, code, "right-verifier"); err == nil {
+		t.Error("Exchange accepted a code twice")
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFakeProvider(t)
+	f.setKeys(rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey))
+	p := NewProvider(f.config())
+
+	with := func(edit func(c *IDTokenClaims)) *IDTokenClaims {
+		c := validClaims()
+		edit(c)
+		return c
+	}
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+		ok    bool
+	}{
+		{"rs256", signToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, validClaims()), testNonce, true},
+		{"es256", signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, validClaims()), testNonce, true},
+		{"issuer without scheme", signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, with(func(c *IDTokenClaims) {
+			c.Issuer = "accounts.example.com"
+		})), testNonce, true},
+		{"nonce mismatch", signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, validClaims()), "other-nonce", false},
+		{"empty nonce", signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, with(func(c *IDTokenClaims) {
+			c.Nonce = ""
+		})), "", false},
+		{"wrong audience", signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, with(func(c *IDTokenClaims) {
+			c.Audience = jwt.ClaimStrings{"someone-else"}
+		})), testNonce, false},
+		{"expired", signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, with(func(c *IDTokenClaims) {
+			c.IssuedAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Hour))
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+		})), testNonce, false},
+		{"no expiry", signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, with(func(c *IDTokenClaims) {
+			c.ExpiresAt = nil
+		})), testNonce, false},
+		{"issued in the future", signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, with(func(c *IDTokenClaims) {
+			c.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		})), testNonce, false},
+		{"wrong issuer", signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, with(func(c *IDTokenClaims) {
+			c.Issuer = "https://evil.example.com"
+		})), testNonce, false},
+		{"no subject", signToken(t, jwt.SigningMethodES256, "ec-1", ecKey, with(func(c *IDTokenClaims) {
+			c.Subject = ""
+		})), testNonce, false},
+		{"signed by another key", signToken(t, jwt.SigningMethodES256, "ec-1", otherKey, validClaims()), testNonce, false},
+		{"hmac", signToken(t, jwt.SigningMethodHS256, "ec-1", []byte("secret"), validClaims()), testNonce, false},
+		{"unsigned", signToken(t, jwt.SigningMethodNone, "ec-1", jwt.UnsafeAllowNoneSignatureType, validClaims()), testNonce, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := p.VerifyIDToken(context.Background(), tt.token, tt.nonce)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("VerifyIDToken: %v", err)
+				}
+				if claims.Subject != "google-sub-1" || claims.Email != "jane@example.com" || !claims.EmailVerified {
+					t.Errorf("unexpected claims %+v", claims)
+				}
+				return
+			}
+			if err == nil {
+				t.Error("VerifyIDToken accepted an invalid token")
+			}
+		})
+	}
+	if n := f.fetches(); n != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", n)
+	}
+}
+
+func TestKeySetDiscoversRotatedKeys(t *testing.T) {
+	oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	encKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	f := newFakeProvider(t)
+	f.setKeys(ecJWK("old", &oldKey.PublicKey))
+	p := NewProvider(f.config())
+	ctx := context.Background()
+
+	if _, err := p.VerifyIDToken(ctx, signToken(t, jwt.SigningMethodES256, "old", oldKey, validClaims()), testNonce); err != nil {
+		t.Fatalf("VerifyIDToken with the initial key: %v", err)
+	}
+
+	// The provider rotates. Encryption keys are published but never used
+	// to verify signatures.
+	enc := ecJWK("enc", &encKey.PublicKey)
+	enc.Use = "enc"
+	f.setKeys(ecJWK("new", &newKey.PublicKey), enc)
+	rotated := signToken(t, jwt.SigningMethodES256, "new", newKey, validClaims())
+
+	// A fetch just happened, so an unknown kid does not trigger another
+	if _, err := p.VerifyIDToken(ctx, rotated, testNonce); err == nil {
+		t.Fatal("VerifyIDToken accepted an unknown kid within the refresh interval")
+	}
+	if n := f.fetches(); n != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", n)
+	}
+
+	p.keys.mu.Lock()
+	p.keys.lastFetch = time.Now().Add(-jwksMinRefresh)
+	p.keys.mu.Unlock()
+
+	if _, err := p.VerifyIDToken(ctx, rotated, testNonce); err != nil {
+		t.Fatalf("VerifyIDToken after rotation: %v", err)
+	}
+	if n := f.fetches(); n != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", n)
+	}
+	if _, err := p.VerifyIDToken(ctx, signToken(t, jwt.SigningMethodES256, "enc", encKey, validClaims()), testNonce); err == nil {
+		t.Error("VerifyIDToken accepted a token signed with an encryption key")
+	}
+	// Keys dropped from the document are forgotten
+	if _, err := p.VerifyIDToken(ctx, signToken(t, jwt.SigningMethodES256, "old", oldKey, validClaims()), testNonce); err == nil {
+		t.Error("VerifyIDToken accepted a key that was rotated out")
+	}
+}
@@ -0,0 +1,65 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for cursors that were tampered with, are
+// malformed or belong to a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// signatureLength is how many bytes of the HMAC-SHA256 tag are kept
+const signatureLength = 16
+
+// Signer signs cursors so clients can hold them without being able to
+// forge positions. Cursors are only readable by servers sharing the key.
+type Signer struct {
+	key []byte
+}
+
+func NewSigner(key []byte) *Signer {
+	return &Signer{
+		key: key,
+	}
+}
+
+// encode serializes v and appends its signature
+func (s *Signer) encode(v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(s.sign(payload)), nil
+}
+
+// decode verifies the signature and unmarshals the payload into v
+func (s *Signer) decode(cursor string, v any) error {
+	encodedPayload, encodedSignature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, s.sign(payload)) {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func (s *Signer) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+	return mac.Sum(nil)[:signatureLength]
+}
@@ -0,0 +1,141 @@
+// Package pagination implements keyset (cursor) pagination over GORM
+// queries. Pages are addressed by opaque signed cursors holding the sort
+// key and id of a boundary row, so inserts between requests neither skip
+// nor repeat rows and deep pages cost the same as the first.
+package pagination
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Params selects a page
+type Params struct {
+	Limit        int    // Page size, clamped to 1..MaxLimit, DefaultLimit when 0
+	Cursor       string // NextCursor or PrevCursor of an earlier page, empty for the first page
+	IncludeTotal bool   // Also count every matching row, which costs a COUNT(*)
+}
+
+// Order describes the sort. Column must be a trusted SQL expression, never
+// user input. Ties are broken on IDColumn in the same direction.
+type Order struct {
+	Column   string
+	Desc     bool
+	IDColumn string // Defaults to "id"
+}
+
+// Page is one page of results
+type Page[T any] struct {
+	Items      []T
+	NextCursor string // Empty on the last page
+	PrevCursor string // Empty on the first page
+	Total      *int64 // Set when Params.IncludeTotal is
+}
+
+// cursor is the signed payload of a cursor string. Order pins it to the
+// sort it was issued for.
+type cursor[K any] struct {
+	Key      K         `json:"k"`
+	ID       uuid.UUID `json:"i"`
+	Backward bool      `json:"b,omitempty"`
+	Order    string    `json:"o"`
+}
+
+// Paginate runs db, which must carry the query's filters but no order,
+// limit or offset, and returns the page selected by params. keyOf returns
+// the sort key and id of a row; K must round-trip through JSON, as
+// time.Time, integers and strings do.
+func Paginate[T any, K any](db *gorm.DB, signer *Signer, order Order, params Params, keyOf func(*T) (K, uuid.UUID)) (*Page[T], error) {
+	limit := params.Limit
+	if limit < 1 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	idColumn := order.IDColumn
+	if idColumn == "" {
+		idColumn = "id"
+	}
+	fingerprint := fmt.Sprintf("%s,%s,%t", order.Column, idColumn, order.Desc)
+
+	var current *cursor[K]
+	if params.Cursor != "" {
+		current = &cursor[K]{}
+		if err := signer.decode(params.Cursor, current); err != nil {
+			return nil, err
+		}
+		if current.Order != fingerprint {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	base := db.Session(&gorm.Session{})
+	page := &Page[T]{}
+	if params.IncludeTotal {
+		var total int64
+		if err := base.Model(new(T)).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	// Walking backwards flips the sort, then the rows are reversed back
+	backward := current != nil && current.Backward
+	desc := order.Desc != backward
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	query := base
+	if current != nil {
+		query = query.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", order.Column, idColumn, comparison), current.Key, current.ID)
+	}
+	var items []T
+	err := query.
+		Order(order.Column + " " + direction).
+		Order(idColumn + " " + direction).
+		Limit(limit + 1).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	page.Items = items
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	// Rows exist after this page when more were found going forwards or
+	// when we came back from a later page, and the same in reverse
+	if hasMore && !backward || backward {
+		key, id := keyOf(&items[len(items)-1])
+		if page.NextCursor, err = signer.encode(cursor[K]{Key: key, ID: id, Order: fingerprint}); err != nil {
+			return nil, err
+		}
+	}
+	if hasMore && backward || !backward && current != nil {
+		key, id := keyOf(&items[0])
+		if page.PrevCursor, err = signer.encode(cursor[K]{Key: key, ID: id, Backward: true, Order: fingerprint}); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,233 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+)
+
+type item struct {
+	ID    uuid.UUID
+	Score int
+}
+
+func keyOf(i *item) (int, uuid.UUID) {
+	return i.Score, i.ID
+}
+
+var byScore = Order{Column: "score", Desc: true}
+
+// fakeTable answers the queries Paginate builds from an in-memory slice,
+// so keyset pages can be walked without a database. It understands the
+// row value comparison, ORDER BY direction and LIMIT that Paginate emits.
+type fakeTable struct {
+	rows    []item
+	queries []string
+}
+
+func newFakeDB(t *testing.T, table *fakeTable) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Callback().Query().Replace("gorm:query", func(tx *gorm.DB) {
+		callbacks.BuildQuerySQL(tx)
+		table.answer(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func (f *fakeTable) answer(tx *gorm.DB) {
+	sql := tx.Statement.SQL.String()
+	f.queries = append(f.queries, sql)
+
+	if count, ok := tx.Statement.Dest.(*int64); ok {
+		*count = int64(len(f.rows))
+		tx.RowsAffected = 1
+		return
+	}
+
+	rows := append([]item{}, f.rows...)
+	desc := strings.Contains(sql, "DESC")
+	less := func(a, b item) bool {
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		return a.ID.String() < b.ID.String()
+	}
+	if strings.Contains(sql, "(score, id)") {
+		boundary := item{Score: tx.Statement.Vars[0].(int), ID: tx.Statement.Vars[1].(uuid.UUID)}
+		filtered := rows[:0]
+		for _, row := range rows {
+			if desc && less(row, boundary) || !desc && less(boundary, row) {
+				filtered = append(filtered, row)
+			}
+		}
+		rows = filtered
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if desc {
+			return less(rows[j], rows[i])
+		}
+		return less(rows[i], rows[j])
+	})
+	if limit := tx.Statement.Clauses["LIMIT"].Expression.(clause.Limit).Limit; len(rows) > *limit {
+		rows = rows[:*limit]
+	}
+	*tx.Statement.Dest.(*[]item) = rows
+	tx.RowsAffected = int64(len(rows))
+}
+
+// testRows returns n rows in the order Paginate returns them by score
+// descending. Pairs of rows share a score so ties are broken on id.
+func testRows(n int) []item {
+	rows := make([]item, n)
+	for i := range rows {
+		rows[i] = item{
+			ID:    uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", n-i)),
+			Score: (n - i) / 2,
+		}
+	}
+	return rows
+}
+
+func scores(items []item) string {
+	parts := make([]string, len(items))
+	for i, it := range items {
+		parts[i] = fmt.Sprintf("%d/%s", it.Score, it.ID.String()[34:])
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestPaginateWalksForwardAndBack(t *testing.T) {
+	all := testRows(7)
+	table := &fakeTable{rows: all}
+	db := newFakeDB(t, table)
+	signer := NewSigner([]byte("key"))
+
+	page := func(cursor string) *Page[item] {
+		t.Helper()
+		p, err := Paginate(db.Model(&item{}), signer, byScore, Params{Limit: 3, Cursor: cursor}, keyOf)
+		if err != nil {
+			t.Fatalf("Paginate: %v", err)
+		}
+		return p
+	}
+
+	first := page("")
+	if scores(first.Items) != scores(all[0:3]) || first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("first page = %s next=%t prev=%t", scores(first.Items), first.NextCursor != "", first.PrevCursor != "")
+	}
+	second := page(first.NextCursor)
+	if scores(second.Items) != scores(all[3:6]) || second.NextCursor == "" || second.PrevCursor == "" {
+		t.Fatalf("second page = %s next=%t prev=%t", scores(second.Items), second.NextCursor != "", second.PrevCursor != "")
+	}
+	last := page(second.NextCursor)
+	if scores(last.Items) != scores(all[6:]) || last.NextCursor != "" || last.PrevCursor == "" {
+		t.Fatalf("last page = %s next=%t prev=%t", scores(last.Items), last.NextCursor != "", last.PrevCursor != "")
+	}
+
+	// Walking back returns the same pages in the same order
+	back := page(last.PrevCursor)
+	if scores(back.Items) != scores(second.Items) || back.NextCursor == "" || back.PrevCursor == "" {
+		t.Fatalf("back to second page = %s next=%t prev=%t", scores(back.Items), back.NextCursor != "", back.PrevCursor != "")
+	}
+	back = page(back.PrevCursor)
+	if scores(back.Items) != scores(first.Items) || back.NextCursor == "" || back.PrevCursor != "" {
+		t.Fatalf("back to first page = %s next=%t prev=%t", scores(back.Items), back.NextCursor != "", back.PrevCursor != "")
+	}
+}
+
+func TestPaginateLimitAndTotal(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		wantItems int
+	}{
+		{"default", 0, DefaultLimit},
+		{"negative", -5, DefaultLimit},
+		{"explicit", 7, 7},
+		{"clamped", MaxLimit + 50, MaxLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &fakeTable{rows: testRows(MaxLimit + 10)}
+			db := newFakeDB(t, table)
+			p, err := Paginate(db.Model(&item{}), NewSigner([]byte("key")), byScore, Params{Limit: tt.limit, IncludeTotal: true}, keyOf)
+			if err != nil {
+				t.Fatalf("Paginate: %v", err)
+			}
+			if len(p.Items) != tt.wantItems {
+				t.Errorf("got %d items, want %d", len(p.Items), tt.wantItems)
+			}
+			if p.Total == nil || *p.Total != int64(MaxLimit+10) {
+				t.Errorf("Total = %v, want %d", p.Total, MaxLimit+10)
+			}
+		})
+	}
+}
+
+func TestPaginateEmpty(t *testing.T) {
+	db := newFakeDB(t, &fakeTable{})
+	p, err := Paginate(db.Model(&item{}), NewSigner([]byte("key")), byScore, Params{}, keyOf)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	if len(p.Items) != 0 || p.NextCursor != "" || p.PrevCursor != "" || p.Total != nil {
+		t.Errorf("empty page = %+v", p)
+	}
+}
+
+func TestPaginateRejectsBadCursors(t *testing.T) {
+	table := &fakeTable{rows: testRows(5)}
+	db := newFakeDB(t, table)
+	signer := NewSigner([]byte("key"))
+	first, err := Paginate(db.Model(&item{}), signer, byScore, Params{Limit: 2}, keyOf)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	cursor := first.NextCursor
+	payload, signature, _ := strings.Cut(cursor, ".")
+
+	tests := []struct {
+		name   string
+		signer *Signer
+		order  Order
+		cursor string
+	}{
+		{"other key", NewSigner([]byte("other")), byScore, cursor},
+		{"other order", signer, Order{Column: "score"}, cursor},
+		{"other column", signer, Order{Column: "created_at", Desc: true}, cursor},
+		{"tampered payload", signer, byScore, "x" + payload[1:] + "." + signature},
+		{"missing signature", signer, byScore, payload},
+		{"garbage", signer, byScore, "!!!.???"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries := len(table.queries)
+			_, err := Paginate(db.Model(&item{}), tt.signer, tt.order, Params{Cursor: tt.cursor}, keyOf)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+			if len(table.queries) != queries {
+				t.Error("a query ran for an invalid cursor")
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Package storage keeps uploaded files behind the BlobStore interface so
+// the backing service can be swapped by configuration
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned when no blob exists under a key
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores immutable blobs under slash-separated keys
+type BlobStore interface {
+	// Put stores data under key, replacing any existing blob
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the blob stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL a stored blob is served from
+	URL(key string) string
+	// KeyFromURL reverses URL, reporting false for URLs this store did not produce
+	KeyFromURL(url string) (string, bool)
+}
+
+// validKey rejects empty keys and keys that could escape their namespace
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// keyFromURL strips baseURL from url and checks what is left is a key
+func keyFromURL(baseURL, url string) (string, bool) {
+	key, ok := strings.CutPrefix(url, baseURL+"/")
+	if !ok || !validKey(key) {
+		return "", false
+	}
+	return key, true
+}